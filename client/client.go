@@ -0,0 +1,345 @@
+// Package client is a Go client for the Kafka request/reply protocol of the
+// sold-item report service.
+//
+// Requests are produced as query-events on the request-topic, and replies
+// are matched to requests by their CorrelationID on the service's
+// response-topic. Chunked replies are reassembled before being returned.
+package client
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"sync"
+	"time"
+
+	"github.com/Shopify/sarama"
+	"github.com/TerrexTech/agg-itemsoldflashsale-report/report"
+	"github.com/TerrexTech/go-eventstore-models/model"
+	"github.com/TerrexTech/go-kafkautils/kafka"
+	"github.com/TerrexTech/uuuid"
+	"github.com/pkg/errors"
+)
+
+// DefaultResponseTopic is the topic on which the service produces responses.
+const DefaultResponseTopic = "agg.report.flashitemsold.response"
+
+// DefaultTimeout is the request-timeout used when neither Config.Timeout
+// nor a context-deadline is set.
+const DefaultTimeout = 30 * time.Second
+
+// ErrTimeout is returned when no reply was received before the
+// request-timeout.
+var ErrTimeout = errors.New("timed out waiting for response")
+
+// ResponseError is returned when the service replied with an error.
+type ResponseError struct {
+	// Code is the ErrorCode of the response.
+	Code    int16
+	Message string
+	// Details are the machine-readable error-details, if the service
+	// provided them.
+	Details *report.ErrorDetails
+}
+
+func (e *ResponseError) Error() string {
+	return fmt.Sprintf("service error (code %d): %s", e.Code, e.Message)
+}
+
+// Config is the configuration for a Client.
+type Config struct {
+	KafkaBrokers []string
+	// RequestTopic is the topic on which query-events are produced.
+	RequestTopic string
+	// ResponseTopic defaults to DefaultResponseTopic.
+	ResponseTopic string
+	// ConsumerGroup for the response-consumer. Every Client must see all
+	// responses, so this must be unique per Client. A unique group is
+	// generated if blank.
+	ConsumerGroup string
+	// Timeout is used for requests whose context has no deadline, and for
+	// joining the consumer-group. Defaults to DefaultTimeout.
+	Timeout time.Duration
+}
+
+// reply is the outcome of a request.
+type reply struct {
+	resp *model.KafkaResponse
+	err  error
+}
+
+type pendingRequest struct {
+	replies   chan reply
+	assembler *report.ChunkAssembler
+}
+
+// Client sends requests to the report service, and waits for their replies.
+// A Client is safe for concurrent use.
+type Client struct {
+	config   Config
+	producer *kafka.Producer
+	consumer *kafka.Consumer
+	cancel   context.CancelFunc
+
+	lock    sync.Mutex
+	pending map[uuuid.UUID]*pendingRequest
+}
+
+// New creates a Client, and waits until its response-consumer has joined
+// the consumer-group, so no replies are missed.
+func New(config Config) (*Client, error) {
+	if len(config.KafkaBrokers) == 0 {
+		return nil, errors.New("KafkaBrokers cannot be blank")
+	}
+	if config.RequestTopic == "" {
+		return nil, errors.New("RequestTopic cannot be blank")
+	}
+	if config.ResponseTopic == "" {
+		config.ResponseTopic = DefaultResponseTopic
+	}
+	if config.Timeout == 0 {
+		config.Timeout = DefaultTimeout
+	}
+	if config.ConsumerGroup == "" {
+		groupID, err := uuuid.NewV4()
+		if err != nil {
+			err = errors.Wrap(err, "Error generating consumer-group")
+			return nil, err
+		}
+		config.ConsumerGroup = config.ResponseTopic + ".client." + groupID.String()
+	}
+
+	producer, err := kafka.NewProducer(&kafka.ProducerConfig{
+		KafkaBrokers: config.KafkaBrokers,
+	})
+	if err != nil {
+		err = errors.Wrap(err, "Error creating request-producer")
+		return nil, err
+	}
+	consumer, err := kafka.NewConsumer(&kafka.ConsumerConfig{
+		KafkaBrokers: config.KafkaBrokers,
+		GroupName:    config.ConsumerGroup,
+		Topics:       []string{config.ResponseTopic},
+	})
+	if err != nil {
+		producer.Close()
+		err = errors.Wrap(err, "Error creating response-consumer")
+		return nil, err
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	c := &Client{
+		config:   config,
+		producer: producer,
+		consumer: consumer,
+		cancel:   cancel,
+		pending:  map[uuuid.UUID]*pendingRequest{},
+	}
+
+	handler := &responseHandler{
+		ready:    make(chan struct{}),
+		callback: c.handleResponse,
+	}
+	go c.consume(ctx, handler)
+	go c.logProducerErrors(ctx)
+
+	select {
+	case <-handler.ready:
+		return c, nil
+	case <-time.After(config.Timeout):
+		c.Close()
+		return nil, errors.New("timed out joining response consumer-group")
+	}
+}
+
+// Close stops the Client. Pending requests fail with their context's error
+// or ErrTimeout.
+func (c *Client) Close() error {
+	c.cancel()
+	consErr := c.consumer.Close()
+	prodErr := c.producer.Close()
+	if consErr != nil {
+		return errors.Wrap(consErr, "Error closing response-consumer")
+	}
+	if prodErr != nil {
+		return errors.Wrap(prodErr, "Error closing request-producer")
+	}
+	return nil
+}
+
+// Request produces the event, and returns the service's reply. JobProgress
+// replies are skipped, so the final reply of async requests is returned.
+// If ctx has no deadline, Config.Timeout is used.
+func (c *Client) Request(ctx context.Context, event *model.Event) (*model.KafkaResponse, error) {
+	if _, ok := ctx.Deadline(); !ok {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, c.config.Timeout)
+		defer cancel()
+	}
+
+	p, err := c.register(event.CorrelationID)
+	if err != nil {
+		return nil, err
+	}
+	defer c.unregister(event.CorrelationID)
+
+	eventMarshal, err := json.Marshal(event)
+	if err != nil {
+		err = errors.Wrap(err, "Error marshalling request-event")
+		return nil, err
+	}
+	select {
+	case c.producer.Input() <- kafka.CreateMessage(c.config.RequestTopic, eventMarshal):
+	case <-ctx.Done():
+		return nil, ctxError(ctx)
+	}
+
+	return c.await(ctx, p)
+}
+
+// register starts tracking replies for the CorrelationID.
+func (c *Client) register(correlationID uuuid.UUID) (*pendingRequest, error) {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+
+	if _, ok := c.pending[correlationID]; ok {
+		return nil, errors.Errorf(
+			"request with CorrelationID %s is already pending", correlationID,
+		)
+	}
+	p := &pendingRequest{
+		replies:   make(chan reply, 1),
+		assembler: &report.ChunkAssembler{},
+	}
+	c.pending[correlationID] = p
+	return p, nil
+}
+
+func (c *Client) unregister(correlationID uuuid.UUID) {
+	c.lock.Lock()
+	delete(c.pending, correlationID)
+	c.lock.Unlock()
+}
+
+// await waits for the reply of the pending request.
+func (c *Client) await(ctx context.Context, p *pendingRequest) (*model.KafkaResponse, error) {
+	select {
+	case r := <-p.replies:
+		return r.resp, r.err
+	case <-ctx.Done():
+		return nil, ctxError(ctx)
+	}
+}
+
+// handleResponse passes the response to the pending request with the same
+// CorrelationID. Responses for other requests are ignored.
+func (c *Client) handleResponse(value []byte) {
+	resp := &model.KafkaResponse{}
+	err := json.Unmarshal(value, resp)
+	if err != nil {
+		err = errors.Wrap(err, "Error unmarshalling KafkaResponse")
+		log.Println(err)
+		return
+	}
+	if resp.ServiceAction == JobProgressAction {
+		return
+	}
+
+	c.lock.Lock()
+	defer c.lock.Unlock()
+
+	p, ok := c.pending[resp.CorrelationID]
+	if !ok {
+		return
+	}
+
+	if resp.Error == "" {
+		if chunk, isChunk := report.ParseChunk(resp.Result); isChunk {
+			complete, err := p.assembler.Add(*chunk)
+			if err != nil {
+				err = errors.Wrap(err, "Error adding response-chunk")
+				c.deliver(resp.CorrelationID, p, reply{err: err})
+				return
+			}
+			if !complete {
+				return
+			}
+			resp.Result, err = p.assembler.Result()
+			if err != nil {
+				err = errors.Wrap(err, "Error reassembling chunked response")
+				c.deliver(resp.CorrelationID, p, reply{err: err})
+				return
+			}
+		}
+	}
+	c.deliver(resp.CorrelationID, p, reply{resp: resp})
+}
+
+// deliver sends the reply to the pending request, and stops tracking it.
+// The lock must be held by the caller.
+func (c *Client) deliver(correlationID uuuid.UUID, p *pendingRequest, r reply) {
+	delete(c.pending, correlationID)
+	p.replies <- r
+}
+
+func (c *Client) consume(ctx context.Context, handler *responseHandler) {
+	for ctx.Err() == nil {
+		err := c.consumer.Consume(ctx, handler)
+		if err != nil && ctx.Err() == nil {
+			err = errors.Wrap(err, "Error consuming responses")
+			log.Println(err)
+			time.Sleep(time.Second)
+		}
+	}
+}
+
+func (c *Client) logProducerErrors(ctx context.Context) {
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case prodErr := <-c.producer.Errors():
+			if prodErr != nil {
+				err := errors.Wrap(prodErr.Err, "Error producing request")
+				log.Println(err)
+			}
+		}
+	}
+}
+
+func ctxError(ctx context.Context) error {
+	if ctx.Err() == context.DeadlineExceeded {
+		return ErrTimeout
+	}
+	return ctx.Err()
+}
+
+// responseHandler is the sarama.ConsumerGroupHandler for responses.
+type responseHandler struct {
+	ready     chan struct{}
+	readyOnce sync.Once
+	callback  func(value []byte)
+}
+
+func (h *responseHandler) Setup(sarama.ConsumerGroupSession) error {
+	h.readyOnce.Do(func() {
+		close(h.ready)
+	})
+	return nil
+}
+
+func (*responseHandler) Cleanup(sarama.ConsumerGroupSession) error {
+	return nil
+}
+
+func (h *responseHandler) ConsumeClaim(
+	session sarama.ConsumerGroupSession,
+	claim sarama.ConsumerGroupClaim,
+) error {
+	for msg := range claim.Messages() {
+		session.MarkMessage(msg, "")
+		h.callback(msg.Value)
+	}
+	return nil
+}
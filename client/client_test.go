@@ -0,0 +1,175 @@
+package client
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/TerrexTech/agg-itemsoldflashsale-report/report"
+	"github.com/TerrexTech/go-eventstore-models/model"
+	"github.com/TerrexTech/uuuid"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+func TestClient(t *testing.T) {
+	RegisterFailHandler(Fail)
+	RunSpecs(t, "Client Suite")
+}
+
+var _ = Describe("Client", func() {
+	var c *Client
+
+	BeforeEach(func() {
+		c = &Client{
+			config:  Config{Timeout: time.Second},
+			pending: map[uuuid.UUID]*pendingRequest{},
+		}
+	})
+
+	marshalResponse := func(resp model.KafkaResponse) []byte {
+		value, err := json.Marshal(resp)
+		Expect(err).ToNot(HaveOccurred())
+		return value
+	}
+
+	It("builds query-events with the action and event-data", func() {
+		params := report.SoldItemParams{
+			Timestamp: &report.Comparator{
+				Gt: 1539315000,
+				Lt: 1541997372,
+			},
+		}
+		event, err := NewSoldItemSummary(params)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(event.ServiceAction).To(Equal(SoldItemSummaryAction))
+		Expect(event.AggregateID).To(Equal(AggregateID))
+		Expect(event.CorrelationID).ToNot(Equal(uuuid.UUID{}))
+
+		data := report.SoldItemParams{}
+		err = json.Unmarshal(event.Data, &data)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(data).To(Equal(params))
+	})
+
+	It("rejects invalid batches", func() {
+		_, err := NewSoldItemBatch([]report.SubQuery{})
+		Expect(err).To(HaveOccurred())
+	})
+
+	It("matches replies by CorrelationID", func() {
+		correlationID, err := uuuid.NewV4()
+		Expect(err).ToNot(HaveOccurred())
+		otherID, err := uuuid.NewV4()
+		Expect(err).ToNot(HaveOccurred())
+		p, err := c.register(correlationID)
+		Expect(err).ToNot(HaveOccurred())
+
+		c.handleResponse(marshalResponse(model.KafkaResponse{
+			CorrelationID: otherID,
+			Result:        []byte(`"other"`),
+		}))
+		c.handleResponse(marshalResponse(model.KafkaResponse{
+			CorrelationID: correlationID,
+			ServiceAction: JobProgressAction,
+			Result:        []byte(`{"status":"running"}`),
+		}))
+		c.handleResponse(marshalResponse(model.KafkaResponse{
+			CorrelationID: correlationID,
+			Result:        []byte(`"mine"`),
+		}))
+
+		resp, err := c.await(context.Background(), p)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(resp.Result).To(Equal([]byte(`"mine"`)))
+		Expect(c.pending).To(BeEmpty())
+	})
+
+	It("reassembles chunked replies", func() {
+		correlationID, err := uuuid.NewV4()
+		Expect(err).ToNot(HaveOccurred())
+		p, err := c.register(correlationID)
+		Expect(err).ToNot(HaveOccurred())
+
+		result := bytes.Repeat([]byte(`{"sku":"test-sku1","soldWeight":101}`), 50)
+		chunks, err := report.SplitResult(result, 500)
+		Expect(err).ToNot(HaveOccurred())
+		for i := len(chunks) - 1; i >= 0; i-- {
+			chunkMarshal, err := json.Marshal(chunks[i])
+			Expect(err).ToNot(HaveOccurred())
+			c.handleResponse(marshalResponse(model.KafkaResponse{
+				CorrelationID: correlationID,
+				Result:        chunkMarshal,
+			}))
+		}
+
+		resp, err := c.await(context.Background(), p)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(resp.Result).To(Equal(result))
+	})
+
+	It("returns ErrTimeout if no reply is received", func() {
+		correlationID, err := uuuid.NewV4()
+		Expect(err).ToNot(HaveOccurred())
+		p, err := c.register(correlationID)
+		Expect(err).ToNot(HaveOccurred())
+
+		ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+		defer cancel()
+		_, err = c.await(ctx, p)
+		Expect(err).To(Equal(ErrTimeout))
+	})
+
+	It("converts error-replies to ResponseError", func() {
+		err := responseError(&model.KafkaResponse{
+			Error:     "boom",
+			ErrorCode: 3,
+		})
+		Expect(err).To(Equal(&ResponseError{
+			Code:    3,
+			Message: "boom",
+		}))
+	})
+	It("includes the error-details of error-replies", func() {
+		err := responseError(&model.KafkaResponse{
+			Error:     "invalid fields",
+			ErrorCode: ValidationError,
+			Result:    []byte(`{"reason":"validation","fields":[{"field":"timestamp.$lt","message":"required"}]}`),
+		})
+		Expect(err).To(Equal(&ResponseError{
+			Code:    ValidationError,
+			Message: "invalid fields",
+			Details: &report.ErrorDetails{
+				Reason: "validation",
+				Fields: []report.FieldError{
+					report.FieldError{
+						Field:   "timestamp.$lt",
+						Message: "required",
+					},
+				},
+			},
+		}))
+	})
+	It("keeps the original event-data of dead-letters", func() {
+		event, err := NewEvent(ReportLookupAction, "not an object")
+		Expect(err).ToNot(HaveOccurred())
+		event.Data = []byte(`{"reportID":`)
+
+		letterMarshal, err := json.Marshal(DeadLetter{
+			Event:     *event,
+			Error:     "invalid fields",
+			ErrorCode: ValidationError,
+			Attempts:  1,
+		})
+		Expect(err).ToNot(HaveOccurred())
+
+		letter := DeadLetter{}
+		err = json.Unmarshal(letterMarshal, &letter)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(letter.Event.Data).To(Equal(event.Data))
+		Expect(letter.Event.UUID).To(Equal(event.UUID))
+		Expect(letter.Attempts).To(Equal(1))
+	})
+})
@@ -0,0 +1,127 @@
+package client
+
+import (
+	"encoding/json"
+	"log"
+	"time"
+
+	"github.com/Shopify/sarama"
+	"github.com/TerrexTech/agg-itemsoldflashsale-report/report"
+	"github.com/TerrexTech/go-eventstore-models/model"
+	"github.com/pkg/errors"
+)
+
+// DeadLetter is an event the service failed to handle, as published on the
+// dead-letter topic. Event is the original event, which can be replayed
+// once the cause of the failure is fixed.
+type DeadLetter struct {
+	Event     model.Event          `json:"event"`
+	Error     string               `json:"error"`
+	ErrorCode int16                `json:"errorCode"`
+	Details   *report.ErrorDetails `json:"details,omitempty"`
+	// Attempts is how often the service tried to handle the event.
+	Attempts int `json:"attempts"`
+	// Timestamp is when the event was dead-lettered, as unix-timestamp.
+	Timestamp int64 `json:"timestamp"`
+}
+
+// deadLetterReadTimeout bounds reading a partition of the dead-letter topic.
+const deadLetterReadTimeout = 30 * time.Second
+
+// ReadDeadLetters reads all dead-letters currently on the topic, from the
+// oldest retained message of each partition. Messages that aren't valid
+// dead-letters are logged and skipped.
+func ReadDeadLetters(brokers []string, topic string) ([]DeadLetter, error) {
+	config := sarama.NewConfig()
+	config.Version = sarama.V2_0_0_0
+	config.Consumer.Return.Errors = true
+	kafkaClient, err := sarama.NewClient(brokers, config)
+	if err != nil {
+		err = errors.Wrap(err, "Error creating Kafka client")
+		return nil, err
+	}
+	defer kafkaClient.Close()
+
+	consumer, err := sarama.NewConsumerFromClient(kafkaClient)
+	if err != nil {
+		err = errors.Wrap(err, "Error creating dead-letter consumer")
+		return nil, err
+	}
+	defer consumer.Close()
+
+	partitions, err := kafkaClient.Partitions(topic)
+	if err != nil {
+		err = errors.Wrapf(err, "Error getting partitions of topic %s", topic)
+		return nil, err
+	}
+
+	letters := []DeadLetter{}
+	for _, partition := range partitions {
+		partLetters, err := readDeadLetterPartition(kafkaClient, consumer, topic, partition)
+		if err != nil {
+			return nil, err
+		}
+		letters = append(letters, partLetters...)
+	}
+	return letters, nil
+}
+
+// readDeadLetterPartition reads the messages of the partition up to its
+// current newest offset.
+func readDeadLetterPartition(
+	kafkaClient sarama.Client,
+	consumer sarama.Consumer,
+	topic string,
+	partition int32,
+) ([]DeadLetter, error) {
+	oldest, err := kafkaClient.GetOffset(topic, partition, sarama.OffsetOldest)
+	if err != nil {
+		err = errors.Wrapf(err, "Error getting oldest offset of partition %d", partition)
+		return nil, err
+	}
+	newest, err := kafkaClient.GetOffset(topic, partition, sarama.OffsetNewest)
+	if err != nil {
+		err = errors.Wrapf(err, "Error getting newest offset of partition %d", partition)
+		return nil, err
+	}
+	letters := []DeadLetter{}
+	if oldest >= newest {
+		return letters, nil
+	}
+
+	partConsumer, err := consumer.ConsumePartition(topic, partition, oldest)
+	if err != nil {
+		err = errors.Wrapf(err, "Error consuming partition %d", partition)
+		return nil, err
+	}
+	defer partConsumer.Close()
+
+	timeout := time.After(deadLetterReadTimeout)
+	for {
+		select {
+		case msg := <-partConsumer.Messages():
+			letter := DeadLetter{}
+			err := json.Unmarshal(msg.Value, &letter)
+			if err != nil {
+				err = errors.Wrapf(
+					err, "Error unmarshalling dead-letter at offset %d of partition %d",
+					msg.Offset, partition,
+				)
+				log.Println(err)
+			} else {
+				letters = append(letters, letter)
+			}
+			if msg.Offset >= newest-1 {
+				return letters, nil
+			}
+
+		case consErr := <-partConsumer.Errors():
+			err = errors.Wrapf(consErr.Err, "Error reading partition %d", partition)
+			return nil, err
+
+		case <-timeout:
+			err = errors.Errorf("timed out reading partition %d", partition)
+			return nil, err
+		}
+	}
+}
@@ -0,0 +1,317 @@
+package client
+
+import (
+	"context"
+	"encoding/json"
+	"time"
+
+	"github.com/TerrexTech/agg-itemsoldflashsale-report/report"
+	"github.com/TerrexTech/go-eventstore-models/model"
+	"github.com/TerrexTech/uuuid"
+	"github.com/pkg/errors"
+)
+
+// AggregateID is the AggregateID of the report service.
+const AggregateID int8 = 14
+
+// ServiceActions handled by the report service.
+const (
+	SoldItemSummaryAction = "SoldItemSummary"
+	SoldItemBatchAction   = "SoldItemBatch"
+	ReportLookupAction    = "ReportLookup"
+	ReportHistoryAction   = "ReportHistory"
+	ReportExportAction    = "ReportExport"
+	ReportChartAction     = "ReportChart"
+	JobProgressAction     = "JobProgress"
+)
+
+// ErrorCodes of ResponseErrors.
+const (
+	InternalError        int16 = 2
+	DatabaseError        int16 = 3
+	UnknownActionError   int16 = 4
+	JobCancelledError    int16 = 5
+	TimeoutError         int16 = 6
+	ValidationError      int16 = 7
+	NotFoundError        int16 = 8
+	UnauthorizedError    int16 = 9
+	PayloadTooLargeError int16 = 10
+)
+
+// reportRequest is the event-data for ReportLookup and ReportExport.
+type reportRequest struct {
+	ReportID string             `json:"reportID"`
+	Format   string             `json:"format,omitempty"`
+	CSV      *report.CSVOptions `json:"csv,omitempty"`
+}
+
+// chartRequest is the event-data for ReportChart.
+type chartRequest struct {
+	ReportID string              `json:"reportID"`
+	Chart    report.ChartOptions `json:"chart"`
+	Store    bool                `json:"store,omitempty"`
+}
+
+// Export formats supported by ReportExport.
+const (
+	ExportFormatJSON = "json"
+	ExportFormatCSV  = "csv"
+	ExportFormatXLSX = "xlsx"
+	ExportFormatHTML = "html"
+)
+
+// NewEvent creates a query-event for the action, with the JSON-marshalled
+// data as event-data, and a new CorrelationID.
+func NewEvent(action string, data interface{}) (*model.Event, error) {
+	dataMarshal, err := json.Marshal(data)
+	if err != nil {
+		err = errors.Wrap(err, "Error marshalling event-data")
+		return nil, err
+	}
+	eventID, err := uuuid.NewV4()
+	if err != nil {
+		err = errors.Wrap(err, "Error generating event-UUID")
+		return nil, err
+	}
+	correlationID, err := uuuid.NewV4()
+	if err != nil {
+		err = errors.Wrap(err, "Error generating CorrelationID")
+		return nil, err
+	}
+
+	return &model.Event{
+		AggregateID:   AggregateID,
+		CorrelationID: correlationID,
+		Data:          dataMarshal,
+		EventAction:   "query",
+		NanoTime:      time.Now().UnixNano(),
+		ServiceAction: action,
+		UUID:          eventID,
+	}, nil
+}
+
+// NewSoldItemSummary creates a SoldItemSummary request.
+func NewSoldItemSummary(params report.SoldItemParams) (*model.Event, error) {
+	return NewEvent(SoldItemSummaryAction, params)
+}
+
+// NewSoldItemBatch creates a SoldItemBatch request.
+func NewSoldItemBatch(queries []report.SubQuery) (*model.Event, error) {
+	err := report.ValidateBatch(queries)
+	if err != nil {
+		err = errors.Wrap(err, "Invalid batch")
+		return nil, err
+	}
+	return NewEvent(SoldItemBatchAction, queries)
+}
+
+// NewReportLookup creates a ReportLookup request.
+func NewReportLookup(reportID uuuid.UUID) (*model.Event, error) {
+	return NewEvent(ReportLookupAction, reportRequest{
+		ReportID: reportID.String(),
+	})
+}
+
+// NewReportHistory creates a ReportHistory request.
+func NewReportHistory(params report.HistoryParams) (*model.Event, error) {
+	return NewEvent(ReportHistoryAction, params)
+}
+
+// NewReportExport creates a ReportExport request.
+func NewReportExport(reportID uuuid.UUID, format string) (*model.Event, error) {
+	return NewEvent(ReportExportAction, reportRequest{
+		ReportID: reportID.String(),
+		Format:   format,
+	})
+}
+
+// NewReportExportCSV creates a ReportExport request for CSV.
+func NewReportExportCSV(reportID uuuid.UUID, opts report.CSVOptions) (*model.Event, error) {
+	err := opts.Validate()
+	if err != nil {
+		err = errors.Wrap(err, "Invalid CSV options")
+		return nil, err
+	}
+	return NewEvent(ReportExportAction, reportRequest{
+		ReportID: reportID.String(),
+		Format:   ExportFormatCSV,
+		CSV:      &opts,
+	})
+}
+
+// NewReportChart creates a ReportChart request. If store is true, the
+// chart is also stored alongside the report.
+func NewReportChart(
+	reportID uuuid.UUID,
+	opts report.ChartOptions,
+	store bool,
+) (*model.Event, error) {
+	err := opts.Validate()
+	if err != nil {
+		err = errors.Wrap(err, "Invalid chart options")
+		return nil, err
+	}
+	return NewEvent(ReportChartAction, chartRequest{
+		ReportID: reportID.String(),
+		Chart:    opts,
+		Store:    store,
+	})
+}
+
+// SoldItemSummary runs a sold-item report.
+func (c *Client) SoldItemSummary(
+	ctx context.Context,
+	params report.SoldItemParams,
+) (*report.QueryResult, error) {
+	event, err := NewSoldItemSummary(params)
+	if err != nil {
+		return nil, err
+	}
+	result := &report.QueryResult{}
+	err = c.call(ctx, event, result)
+	if err != nil {
+		return nil, err
+	}
+	return result, nil
+}
+
+// SoldItemBatch runs the sub-queries, and returns their results in order.
+func (c *Client) SoldItemBatch(
+	ctx context.Context,
+	queries []report.SubQuery,
+) ([]report.SubQueryResult, error) {
+	event, err := NewSoldItemBatch(queries)
+	if err != nil {
+		return nil, err
+	}
+	results := []report.SubQueryResult{}
+	err = c.call(ctx, event, &results)
+	if err != nil {
+		return nil, err
+	}
+	return results, nil
+}
+
+// ReportLookup gets a stored report.
+func (c *Client) ReportLookup(
+	ctx context.Context,
+	reportID uuuid.UUID,
+) (*report.SoldReport, error) {
+	event, err := NewReportLookup(reportID)
+	if err != nil {
+		return nil, err
+	}
+	rep := &report.SoldReport{}
+	err = c.call(ctx, event, rep)
+	if err != nil {
+		return nil, err
+	}
+	return rep, nil
+}
+
+// ReportHistory lists stored reports, newest first.
+func (c *Client) ReportHistory(
+	ctx context.Context,
+	params report.HistoryParams,
+) ([]report.SoldReport, error) {
+	event, err := NewReportHistory(params)
+	if err != nil {
+		return nil, err
+	}
+	reports := []report.SoldReport{}
+	err = c.call(ctx, event, &reports)
+	if err != nil {
+		return nil, err
+	}
+	return reports, nil
+}
+
+// ReportExport returns a stored report rendered in the format.
+func (c *Client) ReportExport(
+	ctx context.Context,
+	reportID uuuid.UUID,
+	format string,
+) ([]byte, error) {
+	event, err := NewReportExport(reportID, format)
+	if err != nil {
+		return nil, err
+	}
+	return c.export(ctx, event)
+}
+
+// ReportExportCSV returns the rows of a stored report as CSV.
+func (c *Client) ReportExportCSV(
+	ctx context.Context,
+	reportID uuuid.UUID,
+	opts report.CSVOptions,
+) ([]byte, error) {
+	event, err := NewReportExportCSV(reportID, opts)
+	if err != nil {
+		return nil, err
+	}
+	return c.export(ctx, event)
+}
+
+// ReportChart returns a chart-image of a stored report, as SVG or PNG.
+func (c *Client) ReportChart(
+	ctx context.Context,
+	reportID uuuid.UUID,
+	opts report.ChartOptions,
+	store bool,
+) ([]byte, error) {
+	event, err := NewReportChart(reportID, opts, store)
+	if err != nil {
+		return nil, err
+	}
+	return c.export(ctx, event)
+}
+
+// export sends the request, and returns the reply's raw result.
+func (c *Client) export(ctx context.Context, event *model.Event) ([]byte, error) {
+	resp, err := c.Request(ctx, event)
+	if err != nil {
+		return nil, err
+	}
+	err = responseError(resp)
+	if err != nil {
+		return nil, err
+	}
+	return resp.Result, nil
+}
+
+// call sends the request, and unmarshals the reply's result into result.
+func (c *Client) call(ctx context.Context, event *model.Event, result interface{}) error {
+	resp, err := c.Request(ctx, event)
+	if err != nil {
+		return err
+	}
+	err = responseError(resp)
+	if err != nil {
+		return err
+	}
+
+	err = json.Unmarshal(resp.Result, result)
+	if err != nil {
+		err = errors.Wrap(err, "Error unmarshalling response-result")
+		return err
+	}
+	return nil
+}
+
+// responseError returns a ResponseError if the response contains an error.
+func responseError(resp *model.KafkaResponse) error {
+	if resp.Error == "" {
+		return nil
+	}
+	respErr := &ResponseError{
+		Code:    resp.ErrorCode,
+		Message: resp.Error,
+	}
+	if len(resp.Result) > 0 {
+		details := &report.ErrorDetails{}
+		if json.Unmarshal(resp.Result, details) == nil {
+			respErr.Details = details
+		}
+	}
+	return respErr
+}
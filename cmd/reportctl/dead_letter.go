@@ -0,0 +1,134 @@
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"log"
+	"os"
+
+	"github.com/Shopify/sarama"
+	"github.com/TerrexTech/agg-itemsoldflashsale-report/client"
+	"github.com/TerrexTech/go-commonutils/commonutil"
+	"github.com/pkg/errors"
+)
+
+// kafkaBrokers returns the brokers from the service's KAFKA_BROKERS.
+func kafkaBrokers() ([]string, error) {
+	brokersStr := os.Getenv("KAFKA_BROKERS")
+	if brokersStr == "" {
+		return nil, errors.New("Env-var KAFKA_BROKERS is required, but is not set")
+	}
+	return *commonutil.ParseHosts(brokersStr), nil
+}
+
+// readDeadLetters reads the dead-letters from the topic, which defaults to
+// the service's DEAD_LETTER_TOPIC.
+func readDeadLetters(topic string) ([]client.DeadLetter, error) {
+	if topic == "" {
+		return nil, errors.New("-topic is required, since DEAD_LETTER_TOPIC is not set")
+	}
+	brokers, err := kafkaBrokers()
+	if err != nil {
+		return nil, err
+	}
+
+	letters, err := client.ReadDeadLetters(brokers, topic)
+	if err != nil {
+		return nil, errors.Wrap(err, "Error reading dead-letters")
+	}
+	return letters, nil
+}
+
+// listDeadLetters writes the events on the dead-letter topic, oldest first
+// per partition.
+func listDeadLetters(args []string) error {
+	fs := flag.NewFlagSet("deadletter list", flag.ExitOnError)
+	topic := fs.String("topic", os.Getenv("DEAD_LETTER_TOPIC"), "dead-letter topic")
+	output := fs.String("o", outputTable, "output format: table, json or csv")
+	fs.Parse(args)
+
+	err := validateOutput(*output)
+	if err != nil {
+		return err
+	}
+	letters, err := readDeadLetters(*topic)
+	if err != nil {
+		return err
+	}
+
+	if *output == outputJSON {
+		return writeJSON(os.Stdout, letters)
+	}
+	return writeDeadLetterList(os.Stdout, *output, letters)
+}
+
+// replayDeadLetters produces the original events of the dead-letters to the
+// request-topic again, so the service handles them anew. Replayed events
+// stay on the dead-letter topic. The request-topic has no default, since it
+// depends on how events reach the service.
+func replayDeadLetters(args []string) error {
+	fs := flag.NewFlagSet("deadletter replay", flag.ExitOnError)
+	topic := fs.String("topic", os.Getenv("DEAD_LETTER_TOPIC"), "dead-letter topic")
+	requestTopic := fs.String(
+		"request-topic", "", "topic to replay the events to (required)",
+	)
+	all := fs.Bool("all", false, "replay all dead-letters")
+	fs.Parse(args)
+
+	if *requestTopic == "" {
+		return errors.New("-request-topic is required")
+	}
+	if !*all && fs.NArg() == 0 {
+		return errors.New("usage: reportctl deadletter replay -request-topic <topic> [-all] [<eventUUID>...]")
+	}
+	eventIDs := map[string]bool{}
+	for _, id := range fs.Args() {
+		eventIDs[id] = true
+	}
+
+	letters, err := readDeadLetters(*topic)
+	if err != nil {
+		return err
+	}
+	brokers, err := kafkaBrokers()
+	if err != nil {
+		return err
+	}
+
+	config := sarama.NewConfig()
+	config.Version = sarama.V2_0_0_0
+	config.Producer.Return.Successes = true
+	producer, err := sarama.NewSyncProducer(brokers, config)
+	if err != nil {
+		return errors.Wrap(err, "Error creating replay-producer")
+	}
+	defer producer.Close()
+
+	replayed := 0
+	for _, letter := range letters {
+		eventID := letter.Event.UUID.String()
+		if !*all && !eventIDs[eventID] {
+			continue
+		}
+		eventMarshal, err := json.Marshal(letter.Event)
+		if err != nil {
+			return errors.Wrapf(err, "Error marshalling event %s", eventID)
+		}
+		_, _, err = producer.SendMessage(&sarama.ProducerMessage{
+			Topic: *requestTopic,
+			Value: sarama.ByteEncoder(eventMarshal),
+		})
+		if err != nil {
+			return errors.Wrapf(err, "Error replaying event %s", eventID)
+		}
+		log.Printf("Replayed event %s (%s)", eventID, letter.Event.ServiceAction)
+		replayed++
+		delete(eventIDs, eventID)
+	}
+
+	log.Printf("Replayed %d events to %s", replayed, *requestTopic)
+	if !*all && len(eventIDs) > 0 {
+		return errors.Errorf("%d events were not found on the dead-letter topic", len(eventIDs))
+	}
+	return nil
+}
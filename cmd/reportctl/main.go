@@ -0,0 +1,336 @@
+// Command reportctl runs sold-item reports and administers stored reports,
+// directly against the service's Mongo collections. It also inspects and
+// replays the events on the service's dead-letter topic.
+//
+// Usage:
+//
+//	reportctl report run -gt <unix> -lt <unix> [flags]
+//	reportctl report get [-o format] <reportID>
+//	reportctl report list [-limit n] [-skip n] [-o format]
+//	reportctl report purge -older-than <duration>
+//	reportctl items export -out <dir> [flags]
+//	reportctl deadletter list [-o format]
+//	reportctl deadletter replay -request-topic <topic> [-all] [<eventUUID>...]
+//
+// Mongo and Kafka are configured using the service's env-vars, which are
+// also read from the file set by -env (default ".env").
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"log"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/TerrexTech/agg-itemsoldflashsale-report/report"
+	"github.com/TerrexTech/uuuid"
+	"github.com/joho/godotenv"
+	"github.com/pkg/errors"
+)
+
+const usage = `Usage:
+  reportctl [-env file] report run -gt <unix> -lt <unix> [flags]
+  reportctl [-env file] report get [-o format] <reportID>
+  reportctl [-env file] report list [-limit n] [-skip n] [-o format]
+  reportctl [-env file] report purge -older-than <duration>
+  reportctl [-env file] items export -out <dir> [flags]
+  reportctl [-env file] deadletter list [-o format]
+  reportctl [-env file] deadletter replay -request-topic <topic> [-all] [<eventUUID>...]
+
+Run "reportctl <group> <command> -h" for the flags of a command.
+`
+
+func main() {
+	log.SetFlags(0)
+	log.SetPrefix("reportctl: ")
+
+	flag.Usage = func() {
+		fmt.Fprint(os.Stderr, usage)
+	}
+	envFile := flag.String("env", ".env", "env-file to read Mongo and Kafka configuration from")
+	flag.Parse()
+
+	args := flag.Args()
+	if len(args) < 2 {
+		flag.Usage()
+		os.Exit(2)
+	}
+
+	err := godotenv.Load(*envFile)
+	if err != nil && *envFile != ".env" {
+		err = errors.Wrap(err, "Error reading env-file")
+		log.Fatalln(err)
+	}
+
+	groups := map[string]map[string]func([]string) error{
+		"report": {
+			"run":   runReport,
+			"get":   getReport,
+			"list":  listReports,
+			"purge": purgeReports,
+		},
+		"items": {
+			"export": exportItems,
+		},
+		"deadletter": {
+			"list":   listDeadLetters,
+			"replay": replayDeadLetters,
+		},
+	}
+	command, ok := groups[args[0]][args[1]]
+	if !ok {
+		flag.Usage()
+		os.Exit(2)
+	}
+	err = command(args[2:])
+	if err != nil {
+		log.Fatalln(err)
+	}
+}
+
+// runReport aggregates the sold-items matching the flags, and optionally
+// stores the result as report.
+func runReport(args []string) error {
+	fs := flag.NewFlagSet("report run", flag.ExitOnError)
+	gt := fs.Int64("gt", 0, "include sold-items after this unix-timestamp (required)")
+	lt := fs.Int64("lt", 0, "include sold-items before this unix-timestamp (required)")
+	sku := fs.String("sku", "", "only include sold-items with this SKU")
+	name := fs.String("name", "", "only include sold-items with this name")
+	lot := fs.String("lot", "", "only include sold-items with this lot")
+	groupBy := fs.String("group-by", "", "comma-separated dimensions: sku, name, lot (default sku,name)")
+	metric := fs.String("metric", "", "avg or sum (default avg)")
+	bucket := fs.String("bucket", "", "group by time-bucket: hour, day or week")
+	sortBy := fs.String("sort-by", "", "soldWeight, totalWeight or bucket")
+	limit := fs.Int64("limit", 0, "maximum number of rows")
+	store := fs.Bool("store", false, "store the result as report")
+	output := fs.String("o", outputTable, "output format: table, json or csv")
+	fs.Parse(args)
+
+	if *gt == 0 || *lt == 0 {
+		return errors.New("-gt and -lt are required")
+	}
+	err := validateOutput(*output)
+	if err != nil {
+		return err
+	}
+
+	params := report.SoldItemParams{
+		Timestamp: &report.Comparator{
+			Gt: float64(*gt),
+			Lt: float64(*lt),
+		},
+	}
+	if *sku != "" {
+		params.SKU = &report.Comparator{Eq: *sku}
+	}
+	if *name != "" {
+		params.Name = &report.Comparator{Eq: *name}
+	}
+	if *lot != "" {
+		params.Lot = &report.Comparator{Eq: *lot}
+	}
+
+	spec := report.AggregationSpec{
+		Metric: *metric,
+		Bucket: *bucket,
+		SortBy: *sortBy,
+		Limit:  *limit,
+	}
+	if *groupBy != "" {
+		spec.GroupBy = strings.Split(*groupBy, ",")
+	}
+	err = spec.Validate()
+	if err != nil {
+		return err
+	}
+
+	colls, err := connectMongo()
+	if err != nil {
+		return err
+	}
+	defer colls.close()
+
+	aggResult, err := report.Aggregate(context.Background(), params, spec, colls.itemSold)
+	if err != nil {
+		return errors.Wrap(err, "Error aggregating sold-items")
+	}
+	var reportID uuuid.UUID
+	if *store {
+		reportID, err = uuuid.NewV4()
+		if err != nil {
+			return errors.Wrap(err, "Error generating reportID")
+		}
+	}
+	rep, rowErrors := report.NewSoldReport(reportID, params, spec, aggResult)
+	for _, rowErr := range rowErrors {
+		log.Printf("Error decoding aggregation-row %d: %s", rowErr.Index, rowErr.Error)
+	}
+
+	result := report.NewQueryResult(rep, rowErrors)
+	if *store {
+		_, err = report.CreateReport(context.Background(), rep, colls.report)
+		if err != nil {
+			return errors.Wrap(err, "Error storing report")
+		}
+		log.Printf("Stored report %s", result.ReportID)
+	} else {
+		// Without a ReportID, the report can't be looked up
+		result.Status = report.ReportStatusPartial
+	}
+
+	if *output == outputJSON {
+		return writeJSON(os.Stdout, result)
+	}
+	return writeRows(os.Stdout, *output, rep.ReportResult)
+}
+
+// getReport writes the stored report.
+func getReport(args []string) error {
+	fs := flag.NewFlagSet("report get", flag.ExitOnError)
+	output := fs.String("o", outputTable, "output format: table, json or csv")
+	fs.Parse(args)
+
+	if fs.NArg() != 1 {
+		return errors.New("usage: reportctl report get [-o format] <reportID>")
+	}
+	err := validateOutput(*output)
+	if err != nil {
+		return err
+	}
+	reportID, err := uuuid.FromString(fs.Arg(0))
+	if err != nil {
+		return errors.Wrap(err, "Error parsing reportID")
+	}
+
+	colls, err := connectMongo()
+	if err != nil {
+		return err
+	}
+	defer colls.close()
+
+	rep, err := report.FindReport(context.Background(), reportID, colls.report)
+	if err != nil {
+		return errors.Wrapf(err, "Error finding report %s", reportID)
+	}
+
+	if *output == outputJSON {
+		return writeJSON(os.Stdout, rep)
+	}
+	return writeRows(os.Stdout, *output, rep.ReportResult)
+}
+
+// listReports writes the stored reports, newest first.
+func listReports(args []string) error {
+	fs := flag.NewFlagSet("report list", flag.ExitOnError)
+	limit := fs.Int64("limit", report.DefaultHistoryLimit, "maximum number of reports")
+	skip := fs.Int64("skip", 0, "number of reports to skip")
+	output := fs.String("o", outputTable, "output format: table, json or csv")
+	fs.Parse(args)
+
+	err := validateOutput(*output)
+	if err != nil {
+		return err
+	}
+
+	colls, err := connectMongo()
+	if err != nil {
+		return err
+	}
+	defer colls.close()
+
+	reports, err := report.ListReports(context.Background(), report.HistoryParams{
+		Limit: *limit,
+		Skip:  *skip,
+	}, colls.report)
+	if err != nil {
+		return errors.Wrap(err, "Error listing reports")
+	}
+
+	if *output == outputJSON {
+		return writeJSON(os.Stdout, reports)
+	}
+	return writeReportList(os.Stdout, *output, reports)
+}
+
+// purgeReports deletes stored reports older than the flag's duration.
+func purgeReports(args []string) error {
+	fs := flag.NewFlagSet("report purge", flag.ExitOnError)
+	olderThan := fs.Duration("older-than", 0, "delete reports generated longer ago than this, e.g. 720h (required)")
+	fs.Parse(args)
+
+	if *olderThan <= 0 {
+		return errors.New("-older-than must be greater than 0")
+	}
+
+	colls, err := connectMongo()
+	if err != nil {
+		return err
+	}
+	defer colls.close()
+
+	before := time.Now().Add(-*olderThan).Unix()
+	deleted, err := report.PurgeReports(context.Background(), before, colls.report)
+	if err != nil {
+		return errors.Wrap(err, "Error purging reports")
+	}
+	log.Printf("Deleted %d reports generated before %s", deleted, formatTime(before))
+	return nil
+}
+
+// exportItems writes the sold-items matching the flags as day-partitioned
+// Parquet-files.
+func exportItems(args []string) error {
+	fs := flag.NewFlagSet("items export", flag.ExitOnError)
+	out := fs.String("out", "", "output-directory of the day-partitions (required)")
+	gt := fs.Int64("gt", 0, "include sold-items after this unix-timestamp")
+	lt := fs.Int64("lt", 0, "include sold-items before this unix-timestamp")
+	sku := fs.String("sku", "", "only include sold-items with this SKU")
+	name := fs.String("name", "", "only include sold-items with this name")
+	lot := fs.String("lot", "", "only include sold-items with this lot")
+	batch := fs.Int64("batch", report.DefaultParquetBatchSize, "number of sold-items read per query")
+	fs.Parse(args)
+
+	if *out == "" {
+		return errors.New("-out is required")
+	}
+
+	params := report.SoldItemParams{}
+	if *gt != 0 || *lt != 0 {
+		params.Timestamp = &report.Comparator{
+			Gt: float64(*gt),
+			Lt: float64(*lt),
+		}
+	}
+	if *sku != "" {
+		params.SKU = &report.Comparator{Eq: *sku}
+	}
+	if *name != "" {
+		params.Name = &report.Comparator{Eq: *name}
+	}
+	if *lot != "" {
+		params.Lot = &report.Comparator{Eq: *lot}
+	}
+
+	colls, err := connectMongo()
+	if err != nil {
+		return err
+	}
+	defer colls.close()
+
+	result, err := report.ExportParquet(context.Background(), report.ParquetExportOptions{
+		Filter:    params,
+		OutputDir: *out,
+		BatchSize: *batch,
+	}, colls.itemSold)
+	if err != nil {
+		return errors.Wrap(err, "Error exporting sold-items")
+	}
+	for _, file := range result.Files {
+		fmt.Println(file)
+	}
+	log.Printf("Exported %d sold-items to %d files", result.Records, len(result.Files))
+	return nil
+}
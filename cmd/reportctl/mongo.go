@@ -0,0 +1,95 @@
+package main
+
+import (
+	"log"
+	"os"
+	"strconv"
+
+	"github.com/TerrexTech/agg-itemsoldflashsale-report/report"
+	"github.com/TerrexTech/go-commonutils/commonutil"
+	"github.com/TerrexTech/go-mongoutils/mongo"
+	"github.com/pkg/errors"
+)
+
+// collections are the Mongo collections used by the report commands.
+type collections struct {
+	client   *mongo.Client
+	itemSold *mongo.Collection
+	report   *mongo.Collection
+}
+
+// connectMongo connects to Mongo using the same env-vars as the service.
+func connectMongo() (*collections, error) {
+	missingVar, err := commonutil.ValidateEnv(
+		"MONGO_HOSTS",
+		"MONGO_DATABASE",
+		"MONGO_AGG_COLLECTION",
+		"MONGO_REPORT_COLLECTION",
+	)
+	if err != nil {
+		err = errors.Wrapf(err, "Env-var %s is required, but is not set", missingVar)
+		return nil, err
+	}
+
+	connTimeout := envInt("MONGO_CONNECTION_TIMEOUT_MS", 3000)
+	resTimeout := envInt("MONGO_RESOURCE_TIMEOUT_MS", 5000)
+
+	client, err := mongo.NewClient(mongo.ClientConfig{
+		Hosts:               *commonutil.ParseHosts(os.Getenv("MONGO_HOSTS")),
+		Username:            os.Getenv("MONGO_USERNAME"),
+		Password:            os.Getenv("MONGO_PASSWORD"),
+		TimeoutMilliseconds: uint32(connTimeout),
+	})
+	if err != nil {
+		err = errors.Wrap(err, "Error creating MongoClient")
+		return nil, err
+	}
+	conn := &mongo.ConnectionConfig{
+		Client:  client,
+		Timeout: uint32(resTimeout),
+	}
+
+	database := os.Getenv("MONGO_DATABASE")
+	itemSoldColl, err := mongo.EnsureCollection(&mongo.Collection{
+		Connection:   conn,
+		Database:     database,
+		Name:         os.Getenv("MONGO_AGG_COLLECTION"),
+		SchemaStruct: &report.FlashSaleSoldItem{},
+	})
+	if err != nil {
+		err = errors.Wrap(err, "Error creating sold-item MongoCollection")
+		return nil, err
+	}
+	reportColl, err := mongo.EnsureCollection(&mongo.Collection{
+		Connection:   conn,
+		Database:     database,
+		Name:         os.Getenv("MONGO_REPORT_COLLECTION"),
+		SchemaStruct: &report.SoldReport{},
+	})
+	if err != nil {
+		err = errors.Wrap(err, "Error creating report MongoCollection")
+		return nil, err
+	}
+
+	return &collections{
+		client:   client,
+		itemSold: itemSoldColl,
+		report:   reportColl,
+	}, nil
+}
+
+func (c *collections) close() {
+	err := c.client.Disconnect()
+	if err != nil {
+		err = errors.Wrap(err, "Error disconnecting MongoClient")
+		log.Println(err)
+	}
+}
+
+func envInt(name string, defaultValue int) int {
+	value, err := strconv.Atoi(os.Getenv(name))
+	if err != nil {
+		return defaultValue
+	}
+	return value
+}
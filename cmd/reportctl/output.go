@@ -0,0 +1,134 @@
+package main
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/TerrexTech/agg-itemsoldflashsale-report/client"
+	"github.com/TerrexTech/agg-itemsoldflashsale-report/report"
+	"github.com/pkg/errors"
+)
+
+// Output formats.
+const (
+	outputTable = "table"
+	outputJSON  = "json"
+	outputCSV   = "csv"
+)
+
+func validateOutput(format string) error {
+	switch format {
+	case outputTable, outputJSON, outputCSV:
+		return nil
+	default:
+		return errors.Errorf("unknown output format: \"%s\"", format)
+	}
+}
+
+// writeRows writes the report-rows in table or CSV format.
+func writeRows(w io.Writer, format string, rows []report.ReportResult) error {
+	header := []string{"sku", "name", "lot", "bucket", "soldWeight", "totalWeight"}
+	records := make([][]string, len(rows))
+	for i, row := range rows {
+		bucket := ""
+		if row.Bucket != 0 {
+			bucket = formatTime(row.Bucket)
+		}
+		records[i] = []string{
+			row.SKU,
+			row.Name,
+			row.Lot,
+			bucket,
+			strconv.FormatFloat(row.SoldWeight, 'f', -1, 64),
+			strconv.FormatFloat(row.TotalWeight, 'f', -1, 64),
+		}
+	}
+	return writeRecords(w, format, header, records)
+}
+
+// writeReportList writes a summary-line per report in table or CSV format.
+func writeReportList(w io.Writer, format string, reports []report.SoldReport) error {
+	header := []string{"reportID", "timestamp", "from", "to", "rows"}
+	records := make([][]string, len(reports))
+	for i, rep := range reports {
+		from, to := "", ""
+		if rep.SearchQuery.Timestamp != nil {
+			from = formatTime(int64(rep.SearchQuery.Timestamp.Gt))
+			to = formatTime(int64(rep.SearchQuery.Timestamp.Lt))
+		}
+		records[i] = []string{
+			rep.ReportID.String(),
+			formatTime(rep.Timestamp),
+			from,
+			to,
+			strconv.Itoa(len(rep.ReportResult)),
+		}
+	}
+	return writeRecords(w, format, header, records)
+}
+
+// writeDeadLetterList writes a summary-line per dead-letter in table or CSV
+// format.
+func writeDeadLetterList(w io.Writer, format string, letters []client.DeadLetter) error {
+	header := []string{"eventUUID", "action", "errorCode", "attempts", "deadLettered", "error"}
+	records := make([][]string, len(letters))
+	for i, letter := range letters {
+		records[i] = []string{
+			letter.Event.UUID.String(),
+			letter.Event.ServiceAction,
+			strconv.Itoa(int(letter.ErrorCode)),
+			strconv.Itoa(letter.Attempts),
+			formatTime(letter.Timestamp),
+			letter.Error,
+		}
+	}
+	return writeRecords(w, format, header, records)
+}
+
+// writeRecords writes the records in table or CSV format. The header is
+// written as is for CSV, and upper-cased for tables.
+func writeRecords(w io.Writer, format string, header []string, records [][]string) error {
+	if format == outputCSV {
+		cw := csv.NewWriter(w)
+		err := cw.Write(header)
+		if err != nil {
+			return errors.Wrap(err, "Error writing CSV header")
+		}
+		err = cw.WriteAll(records)
+		if err != nil {
+			return errors.Wrap(err, "Error writing CSV records")
+		}
+		return nil
+	}
+
+	tw := tabwriter.NewWriter(w, 0, 4, 2, ' ', 0)
+	fmt.Fprintln(tw, strings.ToUpper(strings.Join(header, "\t")))
+	for _, record := range records {
+		fmt.Fprintln(tw, strings.Join(record, "\t"))
+	}
+	err := tw.Flush()
+	if err != nil {
+		return errors.Wrap(err, "Error writing table")
+	}
+	return nil
+}
+
+func writeJSON(w io.Writer, v interface{}) error {
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	err := enc.Encode(v)
+	if err != nil {
+		return errors.Wrap(err, "Error writing JSON")
+	}
+	return nil
+}
+
+func formatTime(unix int64) string {
+	return time.Unix(unix, 0).UTC().Format(time.RFC3339)
+}
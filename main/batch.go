@@ -0,0 +1,77 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+
+	"github.com/TerrexTech/agg-itemsoldflashsale-report/report"
+	"github.com/TerrexTech/go-eventstore-models/model"
+	tlog "github.com/TerrexTech/go-logtransport/log"
+	"github.com/TerrexTech/go-mongoutils/mongo"
+	"github.com/pkg/errors"
+)
+
+// BatchQuery handles "SoldItemBatch" events, which run several named
+// sub-queries and return their results in a single response.
+// event.Data should be in this format:
+// `[{"name":"topSkus","params":{"timestamp":{...}},"aggregation":{"sortBy":"soldWeight","limit":10}}]`
+func BatchQuery(
+	ctx context.Context,
+	logger tlog.Logger,
+	itemSoldColl *mongo.Collection,
+	event *model.Event,
+) *model.KafkaResponse {
+	queries := []report.SubQuery{}
+	err := unmarshalEventData(event.Data, &queries)
+	if err != nil {
+		err = errors.Wrap(err, "BatchQuery: Error while unmarshalling Event-data")
+		logger.E(tlog.Entry{
+			Description: err.Error(),
+			ErrorCode:   1,
+		}, string(event.Data))
+		return errorResponse(event, err, ValidationError)
+	}
+
+	err = report.ValidateBatch(queries)
+	if err != nil {
+		errCode := int16(ValidationError)
+		if errors.Cause(err) == report.ErrBatchTooLarge {
+			errCode = PayloadTooLargeError
+		}
+		err = errors.Wrap(err, "BatchQuery: Invalid batch")
+		logger.E(tlog.Entry{
+			Description: err.Error(),
+			ErrorCode:   1,
+		}, queries)
+		return errorResponse(event, err, errCode)
+	}
+
+	results := report.RunBatch(ctx, queries, itemSoldColl)
+	if ctx.Err() != nil {
+		err = errors.Wrap(ctx.Err(), "BatchQuery: Sub-queries did not complete")
+		logger.E(tlog.Entry{
+			Description: err.Error(),
+			ErrorCode:   1,
+		}, queries)
+		return errorResponse(event, err, contextErrorCode(ctx.Err(), InternalError))
+	}
+	for _, r := range results {
+		if r.Error != "" {
+			logger.E(tlog.Entry{
+				Description: "BatchQuery: Error in sub-query: " + r.Error,
+				ErrorCode:   1,
+			}, r.Name)
+		}
+	}
+
+	resultMarshal, err := json.Marshal(results)
+	if err != nil {
+		err = errors.Wrap(err, "BatchQuery: Error marshalling results")
+		logger.E(tlog.Entry{
+			Description: err.Error(),
+			ErrorCode:   1,
+		}, queries)
+		return errorResponse(event, err, InternalError)
+	}
+	return resultResponse(event, resultMarshal)
+}
@@ -0,0 +1,110 @@
+package main
+
+import (
+	"encoding/json"
+	"log"
+	"os"
+	"strconv"
+
+	"github.com/TerrexTech/agg-itemsoldflashsale-report/report"
+	"github.com/TerrexTech/go-eventstore-models/model"
+	"github.com/pkg/errors"
+)
+
+// defaultMaxMessageBytes matches the default max message-size of Kafka-brokers.
+const defaultMaxMessageBytes = 1000000
+
+// responseOverheadBytes is reserved for KafkaResponse-fields other than Result.
+const responseOverheadBytes = 4096
+
+// loadMaxMessageBytes reads the max message-size for responses from
+// KAFKA_MAX_MESSAGE_BYTES.
+func loadMaxMessageBytes() int {
+	maxBytesStr := os.Getenv("KAFKA_MAX_MESSAGE_BYTES")
+	if maxBytesStr == "" {
+		return defaultMaxMessageBytes
+	}
+	maxBytes, err := strconv.Atoi(maxBytesStr)
+	if err != nil || maxBytes <= responseOverheadBytes {
+		err = errors.Errorf("Invalid KAFKA_MAX_MESSAGE_BYTES: \"%s\"", maxBytesStr)
+		log.Println(err)
+		log.Printf(
+			"A default value of %d will be used for KAFKA_MAX_MESSAGE_BYTES",
+			defaultMaxMessageBytes,
+		)
+		return defaultMaxMessageBytes
+	}
+	return maxBytes
+}
+
+// chunkResponses returns a channel which forwards responses to out. Responses
+// whose Result would exceed maxMessageBytes are split into several responses,
+// each containing a report.ResultChunk as Result.
+// The returned done-channel is closed once the input-channel was closed, and
+// all responses were forwarded.
+func chunkResponses(
+	out chan<- *model.KafkaResponse,
+	maxMessageBytes int,
+) (chan<- *model.KafkaResponse, <-chan struct{}) {
+	in := make(chan *model.KafkaResponse)
+	done := make(chan struct{})
+
+	// Result is base64-encoded in the KafkaResponse, and the chunk-data is
+	// base64-encoded again in the ResultChunk, so each grows by 4/3.
+	maxResultSize := (maxMessageBytes - responseOverheadBytes) * 3 / 4
+	maxChunkSize := maxResultSize * 3 / 4
+
+	go func() {
+		defer close(done)
+		for resp := range in {
+			if len(resp.Result) <= maxResultSize {
+				out <- resp
+				continue
+			}
+
+			chunks, err := splitResponse(resp, maxChunkSize)
+			if err != nil {
+				err = errors.Wrap(err, "Error splitting response into chunks")
+				log.Println(err)
+				out <- errorResponse(&model.Event{
+					AggregateID:   resp.AggregateID,
+					CorrelationID: resp.CorrelationID,
+					EventAction:   resp.EventAction,
+					ServiceAction: resp.ServiceAction,
+					UUID:          resp.UUID,
+				}, err, InternalError)
+				continue
+			}
+			for _, chunk := range chunks {
+				out <- chunk
+			}
+		}
+	}()
+
+	return in, done
+}
+
+// splitResponse splits the response's Result into chunks, and creates a
+// response for each chunk.
+func splitResponse(
+	resp *model.KafkaResponse,
+	maxChunkSize int,
+) ([]*model.KafkaResponse, error) {
+	chunks, err := report.SplitResult(resp.Result, maxChunkSize)
+	if err != nil {
+		return nil, err
+	}
+
+	chunkResps := make([]*model.KafkaResponse, 0, len(chunks))
+	for _, chunk := range chunks {
+		chunkMarshal, err := json.Marshal(chunk)
+		if err != nil {
+			err = errors.Wrapf(err, "Error marshalling chunk %d", chunk.Sequence)
+			return nil, err
+		}
+		chunkResp := *resp
+		chunkResp.Result = chunkMarshal
+		chunkResps = append(chunkResps, &chunkResp)
+	}
+	return chunkResps, nil
+}
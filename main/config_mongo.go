@@ -41,7 +41,7 @@ func loadMongoConfig(collectionName string, schema interface{}) (*poll.MongoConf
 	client, err := mongo.NewClient(mongoConfig)
 	if err != nil {
 		err = errors.Wrap(err, "Error creating MongoClient")
-		log.Println(err)
+		return nil, err
 	}
 
 	resTimeoutStr := os.Getenv("MONGO_CONNECTION_TIMEOUT_MS")
@@ -59,6 +59,7 @@ func loadMongoConfig(collectionName string, schema interface{}) (*poll.MongoConf
 
 	aggMongoCollection, err := createMongoCollection(conn, database, collectionName, schema)
 	if err != nil {
+		client.Disconnect()
 		err = errors.Wrap(err, "Error creating MongoCollection")
 		return nil, err
 	}
@@ -102,14 +103,14 @@ func createMongoCollection(conn *mongo.ConnectionConfig, db string, coll string,
 	return collection, nil
 }
 
-// createClient creates a MongoDB-Client.
+// createClient creates a MongoDB-Client, using the same MONGO_* env-vars as
+// loadMongoConfig.
 func CreateClient() (*mongo.Client, error) {
-	// Would ideally set these config-params as environment vars
 	config := mongo.ClientConfig{
-		Hosts:               []string{"mongo:27017"},
-		Username:            "root",
-		Password:            "root",
-		TimeoutMilliseconds: 3000,
+		Hosts:               *commonutil.ParseHosts(os.Getenv("MONGO_HOSTS")),
+		Username:            os.Getenv("MONGO_USERNAME"),
+		Password:            os.Getenv("MONGO_PASSWORD"),
+		TimeoutMilliseconds: uint32(loadPositiveInt("MONGO_CONNECTION_TIMEOUT_MS", 3000)),
 	}
 
 	// ====> MongoDB Client
@@ -144,7 +145,7 @@ func CreateCollection(client *mongo.Client, collName string, schema interface{})
 	c := &mongo.Collection{
 		Connection:   conn,
 		Name:         collName,
-		Database:     "rns_projections",
+		Database:     os.Getenv("MONGO_DATABASE"),
 		SchemaStruct: schema,
 		Indexes:      indexConfigs,
 	}
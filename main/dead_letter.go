@@ -0,0 +1,188 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"log"
+	"os"
+	"time"
+
+	"github.com/Shopify/sarama"
+	"github.com/TerrexTech/agg-itemsoldflashsale-report/client"
+	"github.com/TerrexTech/go-commonutils/commonutil"
+	"github.com/TerrexTech/go-eventstore-models/model"
+	"github.com/TerrexTech/go-kafkautils/kafka"
+	"github.com/pkg/errors"
+)
+
+// defaultDeadLetterMaxAttempts is how often failing events are handled
+// before they are dead-lettered, if DEAD_LETTER_MAX_ATTEMPTS is not set.
+const defaultDeadLetterMaxAttempts = 3
+
+// deadLetterRetryDelay is the delay before the first retry of a failed
+// event, and grows linearly with the attempts.
+const deadLetterRetryDelay = 500 * time.Millisecond
+
+// DeadLetterQueue publishes events that failed to be handled to the
+// dead-letter topic, with the error and the number of attempts, so they
+// can be inspected and replayed using reportctl.
+type DeadLetterQueue struct {
+	producer *kafka.Producer
+	// input receives the dead-letter messages, and is the producer's input.
+	input       chan<- *sarama.ProducerMessage
+	topic       string
+	maxAttempts int
+}
+
+// loadDeadLetterQueue creates the DeadLetterQueue for DEAD_LETTER_TOPIC.
+// Dead-lettering is disabled, and nil is returned, if the topic is not set.
+func loadDeadLetterQueue() (*DeadLetterQueue, error) {
+	topic := os.Getenv("DEAD_LETTER_TOPIC")
+	if topic == "" {
+		return nil, nil
+	}
+
+	producer, err := kafka.NewProducer(&kafka.ProducerConfig{
+		KafkaBrokers: *commonutil.ParseHosts(os.Getenv("KAFKA_BROKERS")),
+	})
+	if err != nil {
+		err = errors.Wrap(err, "Error creating dead-letter producer")
+		return nil, err
+	}
+	q := &DeadLetterQueue{
+		producer:    producer,
+		input:       producer.Input(),
+		topic:       topic,
+		maxAttempts: loadPositiveInt("DEAD_LETTER_MAX_ATTEMPTS", defaultDeadLetterMaxAttempts),
+	}
+	go q.logProducerErrors()
+	return q, nil
+}
+
+// Dispatch dispatches the event, and retries it while it fails with an
+// internal error, if its ServiceAction is idempotent. Database errors are
+// not retried, since failed Mongo-operations were already retried by the
+// report package. Events still
+// failing after the max attempts or once ctx is done, events failing with a
+// database error, and events whose data can't be parsed are dead-lettered.
+// The final response is returned either way. Without a queue, the event is
+// only dispatched once.
+func (q *DeadLetterQueue) Dispatch(
+	ctx context.Context,
+	dispatcher *Dispatcher,
+	event *model.Event,
+) *model.KafkaResponse {
+	if q == nil {
+		return dispatcher.Dispatch(ctx, event)
+	}
+
+	var resp *model.KafkaResponse
+	attempt := 1
+retry:
+	for ; ; attempt++ {
+		resp = dispatcher.Dispatch(ctx, event)
+		if !isRetryableResponse(event, resp) || attempt >= q.maxAttempts {
+			break
+		}
+
+		timer := time.NewTimer(time.Duration(attempt) * deadLetterRetryDelay)
+		select {
+		case <-timer.C:
+		case <-ctx.Done():
+			timer.Stop()
+			break retry
+		}
+	}
+
+	if isDeadLetterResponse(event, resp) {
+		q.send(event, resp, attempt)
+	}
+	return resp
+}
+
+// send publishes the dead-letter for the failed event.
+func (q *DeadLetterQueue) send(event *model.Event, resp *model.KafkaResponse, attempts int) {
+	letter := client.DeadLetter{
+		Event:     *event,
+		Error:     resp.Error,
+		ErrorCode: resp.ErrorCode,
+		Attempts:  attempts,
+		Timestamp: time.Now().Unix(),
+	}
+	details := errorDetails(errors.New(resp.Error), resp.ErrorCode)
+	if json.Unmarshal(resp.Result, &details) == nil {
+		letter.Details = &details
+	}
+
+	letterMarshal, err := json.Marshal(letter)
+	if err != nil {
+		err = errors.Wrap(err, "Error marshalling dead-letter")
+		log.Println(err)
+		return
+	}
+	q.input <- kafka.CreateMessage(q.topic, letterMarshal)
+	eventsDeadLettered.WithLabelValues(actionLabel(event.ServiceAction)).Inc()
+	log.Printf(
+		"Dead-lettered event %s (%s) after %d attempts: %s",
+		event.UUID, event.ServiceAction, attempts, resp.Error,
+	)
+}
+
+func (q *DeadLetterQueue) logProducerErrors() {
+	for prodErr := range q.producer.Errors() {
+		if prodErr != nil {
+			err := errors.Wrap(prodErr.Err, "Error producing dead-letter")
+			log.Println(err)
+		}
+	}
+}
+
+// Close closes the dead-letter producer.
+func (q *DeadLetterQueue) Close() error {
+	return q.producer.Close()
+}
+
+// idempotentActions are the ServiceActions that can be handled again
+// without side-effects. SoldItemSummary isn't, since each attempt stores
+// another report, or submits another job.
+var idempotentActions = map[string]bool{
+	SoldItemBatchAction: true,
+	ReportLookupAction:  true,
+	ReportHistoryAction: true,
+	ReportExportAction:  true,
+	ReportChartAction:   true,
+	JobStatusAction:     true,
+	JobCancelAction:     true,
+}
+
+// isRetryableResponse returns true if the event failed with an error that
+// might not occur when handling it again, and handling it again is safe.
+func isRetryableResponse(event *model.Event, resp *model.KafkaResponse) bool {
+	if resp == nil || !idempotentActions[event.ServiceAction] {
+		return false
+	}
+	return resp.ErrorCode == InternalError
+}
+
+// isDeadLetterResponse returns true if the event failed in a way that
+// needs to be looked into, rather than being rejected as invalid request.
+func isDeadLetterResponse(event *model.Event, resp *model.KafkaResponse) bool {
+	if resp == nil || resp.Error == "" {
+		return false
+	}
+	if resp.ErrorCode == InternalError || resp.ErrorCode == DatabaseError {
+		return true
+	}
+	return resp.ErrorCode == ValidationError && !parsesEventData(event)
+}
+
+// parsesEventData returns true if the event-data parses into the Event-data
+// type of the event's ServiceAction. Events with data that isn't JSON, or
+// that doesn't match the type, are malformed rather than invalid requests.
+func parsesEventData(event *model.Event) bool {
+	newData, ok := serviceActions[event.ServiceAction]
+	if !ok {
+		return true
+	}
+	return json.Unmarshal(event.Data, newData()) == nil
+}
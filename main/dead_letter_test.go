@@ -0,0 +1,149 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"time"
+
+	"github.com/Shopify/sarama"
+	"github.com/TerrexTech/agg-itemsoldflashsale-report/client"
+	"github.com/TerrexTech/go-eventstore-models/model"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	"github.com/pkg/errors"
+)
+
+var _ = Describe("DeadLetterQueue", func() {
+	var (
+		input      chan *sarama.ProducerMessage
+		q          *DeadLetterQueue
+		dispatcher *Dispatcher
+		attempts   int
+	)
+
+	BeforeEach(func() {
+		input = make(chan *sarama.ProducerMessage, 10)
+		q = &DeadLetterQueue{
+			input:       input,
+			topic:       "dead-letters",
+			maxAttempts: 2,
+		}
+		dispatcher = NewDispatcher(time.Second)
+		attempts = 0
+	})
+
+	// registerError registers a handler for the action, which fails with
+	// the error-code.
+	registerError := func(action string, errCode int16) {
+		dispatcher.Register(action, func(_ context.Context, event *model.Event) *model.KafkaResponse {
+			attempts++
+			return errorResponse(event, errors.New("handler failed"), errCode)
+		})
+	}
+
+	// receiveLetter returns the dead-letter produced by the queue.
+	receiveLetter := func() client.DeadLetter {
+		var msg *sarama.ProducerMessage
+		Expect(input).To(Receive(&msg))
+		Expect(msg.Topic).To(Equal("dead-letters"))
+		value, err := msg.Value.Encode()
+		Expect(err).ToNot(HaveOccurred())
+
+		letter := client.DeadLetter{}
+		err = json.Unmarshal(value, &letter)
+		Expect(err).ToNot(HaveOccurred())
+		return letter
+	}
+
+	It("dead-letters events still failing with internal errors after the max attempts", func() {
+		registerError(ReportLookupAction, InternalError)
+		event := &model.Event{
+			ServiceAction: ReportLookupAction,
+			Data:          []byte(`{"reportID":"id"}`),
+		}
+
+		resp := q.Dispatch(context.Background(), dispatcher, event)
+		Expect(resp.ErrorCode).To(Equal(int16(InternalError)))
+		Expect(attempts).To(Equal(2))
+
+		letter := receiveLetter()
+		Expect(letter.Event.Data).To(Equal(event.Data))
+		Expect(letter.ErrorCode).To(Equal(int16(InternalError)))
+		Expect(letter.Attempts).To(Equal(2))
+		Expect(letter.Details.Reason).To(Equal("internal"))
+	})
+
+	It("dead-letters database errors without dispatching them again", func() {
+		registerError(ReportLookupAction, DatabaseError)
+		event := &model.Event{
+			ServiceAction: ReportLookupAction,
+			Data:          []byte(`{"reportID":"id"}`),
+		}
+
+		q.Dispatch(context.Background(), dispatcher, event)
+		Expect(attempts).To(Equal(1))
+
+		letter := receiveLetter()
+		Expect(letter.ErrorCode).To(Equal(int16(DatabaseError)))
+		Expect(letter.Attempts).To(Equal(1))
+	})
+
+	It("doesn't retry actions with side-effects", func() {
+		registerError(SoldItemSummaryAction, InternalError)
+		q.Dispatch(context.Background(), dispatcher, &model.Event{
+			ServiceAction: SoldItemSummaryAction,
+			Data:          []byte(`{}`),
+		})
+		Expect(attempts).To(Equal(1))
+
+		letter := receiveLetter()
+		Expect(letter.ErrorCode).To(Equal(int16(InternalError)))
+		Expect(letter.Attempts).To(Equal(1))
+	})
+
+	It("stops retrying once ctx is done", func() {
+		q.maxAttempts = 5
+		registerError(ReportHistoryAction, InternalError)
+		ctx, cancel := context.WithCancel(context.Background())
+		cancel()
+
+		q.Dispatch(ctx, dispatcher, &model.Event{
+			ServiceAction: ReportHistoryAction,
+			Data:          []byte(`{}`),
+		})
+		Expect(attempts).To(Equal(1))
+		Expect(receiveLetter().Attempts).To(Equal(1))
+	})
+
+	It("dead-letters event-data that doesn't parse into the request", func() {
+		registerError(ReportLookupAction, ValidationError)
+
+		q.Dispatch(context.Background(), dispatcher, &model.Event{
+			ServiceAction: ReportLookupAction,
+			Data:          []byte(`not JSON`),
+		})
+		Expect(receiveLetter().ErrorCode).To(Equal(int16(ValidationError)))
+
+		q.Dispatch(context.Background(), dispatcher, &model.Event{
+			ServiceAction: ReportLookupAction,
+			Data:          []byte(`{"reportID":5}`),
+		})
+		Expect(receiveLetter().ErrorCode).To(Equal(int16(ValidationError)))
+	})
+
+	It("doesn't dead-letter invalid requests or handled events", func() {
+		registerError(ReportLookupAction, ValidationError)
+		q.Dispatch(context.Background(), dispatcher, &model.Event{
+			ServiceAction: ReportLookupAction,
+			Data:          []byte(`{"reportID":"not a uuid"}`),
+		})
+
+		dispatcher.Register(ReportHistoryAction, func(_ context.Context, event *model.Event) *model.KafkaResponse {
+			return resultResponse(event, []byte(`[]`))
+		})
+		q.Dispatch(context.Background(), dispatcher, &model.Event{
+			ServiceAction: ReportHistoryAction,
+		})
+		Expect(input).ToNot(Receive())
+	})
+})
@@ -0,0 +1,178 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"time"
+
+	"github.com/TerrexTech/agg-itemsoldflashsale-report/report"
+	"github.com/TerrexTech/go-eventstore-models/model"
+	tlog "github.com/TerrexTech/go-logtransport/log"
+	"github.com/TerrexTech/go-mongoutils/mongo"
+	"github.com/pkg/errors"
+)
+
+// ServiceActions handled by this service.
+const (
+	// SoldItemSummaryAction generates a new sold-item report.
+	SoldItemSummaryAction = "SoldItemSummary"
+	// SoldItemBatchAction runs several named sold-item sub-queries.
+	SoldItemBatchAction = "SoldItemBatch"
+	// ReportLookupAction fetches a stored report by its reportID.
+	ReportLookupAction = "ReportLookup"
+	// ReportHistoryAction lists the stored reports.
+	ReportHistoryAction = "ReportHistory"
+	// ReportExportAction renders a stored report in the requested format.
+	ReportExportAction = "ReportExport"
+	// ReportChartAction renders a chart-image of a stored report.
+	ReportChartAction = "ReportChart"
+	// JobStatusAction returns the status of an asynchronous report-job.
+	JobStatusAction = "JobStatus"
+	// JobCancelAction cancels an asynchronous report-job.
+	JobCancelAction = "JobCancel"
+)
+
+// serviceActions maps all ServiceActions handled by this service to a
+// func creating their Event-data.
+var serviceActions = map[string]func() interface{}{
+	SoldItemSummaryAction: func() interface{} { return &soldItemRequest{} },
+	SoldItemBatchAction:   func() interface{} { return &[]report.SubQuery{} },
+	ReportLookupAction:    func() interface{} { return &reportRequest{} },
+	ReportHistoryAction:   func() interface{} { return &report.HistoryParams{} },
+	ReportExportAction:    func() interface{} { return &reportRequest{} },
+	ReportChartAction:     func() interface{} { return &chartRequest{} },
+	JobStatusAction:       func() interface{} { return &jobRequest{} },
+	JobCancelAction:       func() interface{} { return &jobRequest{} },
+}
+
+// defaultRequestTimeoutMS is the request-deadline if REQUEST_TIMEOUT_MS is
+// not set.
+const defaultRequestTimeoutMS = 60000
+
+// maxRequestTimeout limits the timeout events can set for themselves.
+const maxRequestTimeout = 10 * time.Minute
+
+// loadRequestTimeout reads the default request-deadline from
+// REQUEST_TIMEOUT_MS.
+func loadRequestTimeout() time.Duration {
+	timeoutMS := loadPositiveInt("REQUEST_TIMEOUT_MS", defaultRequestTimeoutMS)
+	return time.Duration(timeoutMS) * time.Millisecond
+}
+
+// requestTimeout is the optional "timeoutMS" field of event-data, which
+// sets the request-deadline for the event.
+type requestTimeout struct {
+	TimeoutMS int64 `json:"timeoutMS,omitempty"`
+}
+
+// duration returns the requested timeout, capped at maxRequestTimeout, or
+// zero if none was requested.
+func (r requestTimeout) duration() time.Duration {
+	if r.TimeoutMS <= 0 {
+		return 0
+	}
+	timeout := time.Duration(r.TimeoutMS) * time.Millisecond
+	if timeout > maxRequestTimeout {
+		return maxRequestTimeout
+	}
+	return timeout
+}
+
+// eventTimeout returns the timeout set in the event-data, or defaultTimeout.
+func eventTimeout(event *model.Event, defaultTimeout time.Duration) time.Duration {
+	req := requestTimeout{}
+	// Event-data that isn't an object, such as batches, can't set a timeout
+	err := json.Unmarshal(event.Data, &req)
+	if err != nil || req.duration() == 0 {
+		return defaultTimeout
+	}
+	return req.duration()
+}
+
+// ActionHandler handles a query-event for a specific ServiceAction.
+// The ctx is done once the request-deadline expires.
+type ActionHandler func(ctx context.Context, event *model.Event) *model.KafkaResponse
+
+// Dispatcher routes query-events to ActionHandlers using the
+// ServiceAction of the event.
+type Dispatcher struct {
+	handlers map[string]ActionHandler
+	timeout  time.Duration
+}
+
+// NewDispatcher creates a Dispatcher without any registered handlers.
+// Events are handled with the provided request-timeout, unless they set
+// their own timeout.
+func NewDispatcher(timeout time.Duration) *Dispatcher {
+	return &Dispatcher{
+		handlers: map[string]ActionHandler{},
+		timeout:  timeout,
+	}
+}
+
+// Register sets the handler for the specified ServiceAction,
+// replacing any existing handler for that action.
+func (d *Dispatcher) Register(serviceAction string, handler ActionHandler) {
+	d.handlers[serviceAction] = handler
+}
+
+// Dispatch runs the handler registered for the event's ServiceAction, with
+// the request-deadline applied to ctx.
+// Events with unknown ServiceActions get an UnknownActionError response,
+// and events whose handler panicked get an InternalError response.
+func (d *Dispatcher) Dispatch(ctx context.Context, event *model.Event) (resp *model.KafkaResponse) {
+	handler, ok := d.handlers[event.ServiceAction]
+	if !ok {
+		err := errors.Errorf("unknown ServiceAction: \"%s\"", event.ServiceAction)
+		return errorResponse(event, err, UnknownActionError)
+	}
+
+	defer func() {
+		if recovered := recover(); recovered != nil {
+			err := eventPanic(recovered, event)
+			resp = errorResponse(event, err, InternalError)
+		}
+	}()
+
+	ctx, cancel := context.WithTimeout(ctx, eventTimeout(event, d.timeout))
+	defer cancel()
+	return handler(ctx, event)
+}
+
+// newServiceDispatcher creates a Dispatcher with handlers for all
+// ServiceActions supported by this service.
+func newServiceDispatcher(
+	logger tlog.Logger,
+	itemSoldColl *mongo.Collection,
+	reportColl *mongo.Collection,
+	jobs *JobManager,
+	timeout time.Duration,
+) *Dispatcher {
+	d := NewDispatcher(timeout)
+
+	d.Register(SoldItemSummaryAction, func(ctx context.Context, event *model.Event) *model.KafkaResponse {
+		return Query(ctx, logger, itemSoldColl, reportColl, jobs, event)
+	})
+	d.Register(SoldItemBatchAction, func(ctx context.Context, event *model.Event) *model.KafkaResponse {
+		return BatchQuery(ctx, logger, itemSoldColl, event)
+	})
+	d.Register(ReportLookupAction, func(ctx context.Context, event *model.Event) *model.KafkaResponse {
+		return ReportLookup(ctx, logger, reportColl, event)
+	})
+	d.Register(ReportHistoryAction, func(ctx context.Context, event *model.Event) *model.KafkaResponse {
+		return ReportHistory(ctx, logger, reportColl, event)
+	})
+	d.Register(ReportExportAction, func(ctx context.Context, event *model.Event) *model.KafkaResponse {
+		return ReportExport(ctx, logger, reportColl, event)
+	})
+	d.Register(ReportChartAction, func(ctx context.Context, event *model.Event) *model.KafkaResponse {
+		return ReportChart(ctx, logger, reportColl, event)
+	})
+
+	jobQuery := func(_ context.Context, event *model.Event) *model.KafkaResponse {
+		return JobQuery(jobs, event)
+	}
+	d.Register(JobStatusAction, jobQuery)
+	d.Register(JobCancelAction, jobQuery)
+	return d
+}
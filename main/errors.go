@@ -1,8 +1,149 @@
 package main
 
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+
+	"github.com/TerrexTech/agg-itemsoldflashsale-report/report"
+	"github.com/TerrexTech/go-eventstore-models/model"
+	"github.com/pkg/errors"
+)
+
 // InternalError represents an error when something goes wrong, and its our fault.
 const InternalError = 2
 
 // DatabaseError is when some operation related to Database, such as insert or find,
 // goes wrong and the task cannot proceed.
 const DatabaseError = 3
+
+// UnknownActionError is when the ServiceAction of an event is not handled
+// by this service.
+const UnknownActionError = 4
+
+// JobCancelledError is when an asynchronous report-job was cancelled before
+// it completed.
+const JobCancelledError = 5
+
+// TimeoutError is when the request-deadline expired before the request
+// completed.
+const TimeoutError = 6
+
+// ValidationError is when the request is malformed or has invalid fields.
+// The error-details list the invalid fields.
+const ValidationError = 7
+
+// NotFoundError is when the requested report or job doesn't exist.
+const NotFoundError = 8
+
+// UnauthorizedError is when the caller is not allowed to make the request.
+// This service doesn't authorize requests itself, so the code is reserved for
+// gateways in front of it.
+const UnauthorizedError = 9
+
+// PayloadTooLargeError is when the request exceeds a size-limit, such as
+// the max HTTP body-size or the max sub-queries per batch.
+const PayloadTooLargeError = 10
+
+// errorReasons are the error-categories in ErrorDetails per error-code.
+var errorReasons = map[int16]string{
+	InternalError:        "internal",
+	DatabaseError:        "database",
+	UnknownActionError:   "unknown-action",
+	JobCancelledError:    "job-cancelled",
+	TimeoutError:         "timeout",
+	ValidationError:      "validation",
+	NotFoundError:        "not-found",
+	UnauthorizedError:    "unauthorized",
+	PayloadTooLargeError: "payload-too-large",
+}
+
+// errorDetails describes the error for clients.
+func errorDetails(err error, errorCode int16) report.ErrorDetails {
+	details := report.ErrorDetails{
+		Reason: errorReasons[errorCode],
+	}
+	if details.Reason == "" {
+		details.Reason = errorReasons[InternalError]
+	}
+	details.Fields, _ = report.InvalidFields(err)
+	return details
+}
+
+// validationErrorCode returns ValidationError if err was caused by invalid
+// fields, and errorCode otherwise.
+func validationErrorCode(err error, errorCode int16) int16 {
+	if _, ok := report.InvalidFields(err); ok {
+		return ValidationError
+	}
+	return errorCode
+}
+
+// invalidField creates an error for a single invalid field.
+func invalidField(field string, format string, args ...interface{}) error {
+	return &report.InvalidFieldsError{
+		Fields: []report.FieldError{
+			report.FieldError{
+				Field:   field,
+				Message: fmt.Sprintf(format, args...),
+			},
+		},
+	}
+}
+
+// unmarshalEventData unmarshals the event-data into v. Malformed data is
+// returned as invalid field, with the offending field if known.
+func unmarshalEventData(data []byte, v interface{}) error {
+	err := json.Unmarshal(data, v)
+	if err == nil {
+		return nil
+	}
+
+	field := ""
+	if typeErr, ok := err.(*json.UnmarshalTypeError); ok {
+		field = typeErr.Field
+	}
+	return invalidField(field, "%s", err.Error())
+}
+
+// contextErrorCode returns TimeoutError if err was caused by an expired
+// deadline, and errorCode otherwise.
+func contextErrorCode(err error, errorCode int16) int16 {
+	if errors.Cause(err) == context.DeadlineExceeded {
+		return TimeoutError
+	}
+	return errorCode
+}
+
+// errorResponse creates a KafkaResponse for the event, containing the error.
+// The Result contains the report.ErrorDetails of the error.
+func errorResponse(event *model.Event, err error, errorCode int16) *model.KafkaResponse {
+	detailsMarshal, marshalErr := json.Marshal(errorDetails(err, errorCode))
+	if marshalErr != nil {
+		marshalErr = errors.Wrap(marshalErr, "Error marshalling error-details")
+		log.Println(marshalErr)
+	}
+	return &model.KafkaResponse{
+		AggregateID:   event.AggregateID,
+		CorrelationID: event.CorrelationID,
+		Error:         err.Error(),
+		ErrorCode:     errorCode,
+		EventAction:   event.EventAction,
+		Result:        detailsMarshal,
+		ServiceAction: event.ServiceAction,
+		UUID:          event.UUID,
+	}
+}
+
+// resultResponse creates a KafkaResponse for the event, containing the result.
+func resultResponse(event *model.Event, result []byte) *model.KafkaResponse {
+	return &model.KafkaResponse{
+		AggregateID:   event.AggregateID,
+		CorrelationID: event.CorrelationID,
+		EventAction:   event.EventAction,
+		Result:        result,
+		ServiceAction: event.ServiceAction,
+		UUID:          event.UUID,
+	}
+}
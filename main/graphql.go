@@ -0,0 +1,479 @@
+package main
+
+import (
+	"context"
+	"strings"
+
+	"github.com/TerrexTech/agg-itemsoldflashsale-report/report"
+	tlog "github.com/TerrexTech/go-logtransport/log"
+	"github.com/TerrexTech/go-mongoutils/mongo"
+	"github.com/TerrexTech/uuuid"
+	graphql "github.com/graph-gophers/graphql-go"
+	"github.com/graph-gophers/graphql-go/relay"
+	"github.com/pkg/errors"
+)
+
+// graphqlSchema describes the sold-item aggregations and stored reports.
+// Aggregations are run using the report package's aggregation-builder, so
+// the dimensions, metrics and buckets match those of SoldItemBatch events.
+const graphqlSchema = `
+schema {
+	query: Query
+}
+
+type Query {
+	# Aggregates the sold-items matching the filter. Results are not stored.
+	soldItems(filter: SoldItemFilter!, aggregation: Aggregation): SoldItems!
+	# Stored report by its reportID, or null if no such report exists.
+	report(reportID: ID!): Report
+	# Stored reports, newest first.
+	reports(limit: Int, skip: Int): [Report!]!
+}
+
+input RangeInput {
+	gt: Float!
+	lt: Float!
+}
+
+input SoldItemFilter {
+	timestamp: RangeInput!
+	sku: String
+	name: String
+	lot: String
+}
+
+enum Dimension {
+	SKU
+	NAME
+	LOT
+}
+
+enum Metric {
+	AVG
+	SUM
+}
+
+enum Bucket {
+	HOUR
+	DAY
+	WEEK
+}
+
+enum SortField {
+	SOLD_WEIGHT
+	TOTAL_WEIGHT
+	BUCKET
+}
+
+input Aggregation {
+	groupBy: [Dimension!]
+	metric: Metric
+	bucket: Bucket
+	sortBy: SortField
+	limit: Int
+}
+
+type Range {
+	gt: Float
+	lt: Float
+}
+
+type SearchQuery {
+	timestamp: Range
+	sku: String
+	name: String
+	lot: String
+}
+
+type ReportRow {
+	sku: String
+	name: String
+	lot: String
+	# Start of the time-bucket, as unix-timestamp.
+	bucket: Float
+	soldWeight: Float!
+	totalWeight: Float!
+}
+
+type RowError {
+	index: Int!
+	error: String!
+}
+
+type SoldItems {
+	rows: [ReportRow!]!
+	rowErrors: [RowError!]!
+}
+
+type Report {
+	reportID: ID!
+	timestamp: Float!
+	searchQuery: SearchQuery!
+	# Whether no sold-items matched the searchQuery.
+	empty: Boolean!
+	rows: [ReportRow!]!
+	# Report-rows as CSV. Columns default to all columns except sellThrough.
+	csv(columns: [String!], locale: String, decimals: Int, comments: Boolean): String!
+}
+`
+
+// graphqlSortFields maps SortField enum-values to aggregation sort-fields.
+var graphqlSortFields = map[string]string{
+	"SOLD_WEIGHT":  report.SortSoldWeight,
+	"TOTAL_WEIGHT": report.SortTotalWeight,
+	"BUCKET":       report.SortBucket,
+}
+
+// newGraphQLHandler creates the http.Handler serving GraphQL queries.
+func newGraphQLHandler(
+	logger tlog.Logger,
+	itemSoldColl *mongo.Collection,
+	reportColl *mongo.Collection,
+) (*relay.Handler, error) {
+	schema, err := graphql.ParseSchema(graphqlSchema, &graphqlResolver{
+		logger:       logger,
+		itemSoldColl: itemSoldColl,
+		reportColl:   reportColl,
+	})
+	if err != nil {
+		err = errors.Wrap(err, "Error parsing GraphQL schema")
+		return nil, err
+	}
+	return &relay.Handler{Schema: schema}, nil
+}
+
+// graphqlResolver is the root-resolver for GraphQL queries.
+type graphqlResolver struct {
+	logger       tlog.Logger
+	itemSoldColl *mongo.Collection
+	reportColl   *mongo.Collection
+}
+
+type rangeInput struct {
+	Gt float64
+	Lt float64
+}
+
+type soldItemFilterInput struct {
+	Timestamp rangeInput
+	SKU       *string
+	Name      *string
+	Lot       *string
+}
+
+type aggregationInput struct {
+	GroupBy *[]string
+	Metric  *string
+	Bucket  *string
+	SortBy  *string
+	Limit   *int32
+}
+
+// SoldItems resolves the "soldItems" query.
+func (r *graphqlResolver) SoldItems(ctx context.Context, args struct {
+	Filter      soldItemFilterInput
+	Aggregation *aggregationInput
+}) (*soldItemsResolver, error) {
+	filter := soldItemParamsFromGraphQL(args.Filter)
+	spec := aggregationSpecFromGraphQL(args.Aggregation)
+
+	aggResult, err := report.Aggregate(ctx, filter, spec, r.itemSoldColl)
+	if err != nil {
+		err = errors.Wrap(err, "GraphQL: Error aggregating sold-items")
+		r.logger.E(tlog.Entry{
+			Description: err.Error(),
+			ErrorCode:   1,
+		}, filter, spec)
+		return nil, err
+	}
+
+	rows, rowErrors := report.DecodeAggregateRows(aggResult)
+	return &soldItemsResolver{
+		rows:      rows,
+		rowErrors: rowErrors,
+	}, nil
+}
+
+// Report resolves the "report" query.
+func (r *graphqlResolver) Report(ctx context.Context, args struct {
+	ReportID graphql.ID
+}) (*soldReportResolver, error) {
+	reportID, err := uuuid.FromString(string(args.ReportID))
+	if err != nil {
+		err = errors.Wrap(err, "GraphQL: Error parsing reportID")
+		return nil, err
+	}
+
+	rep, err := report.FindReport(ctx, reportID, r.reportColl)
+	if err == report.ErrReportNotFound {
+		return nil, nil
+	}
+	if err != nil {
+		err = errors.Wrap(err, "GraphQL: Error finding report")
+		r.logger.E(tlog.Entry{
+			Description: err.Error(),
+			ErrorCode:   1,
+		}, args.ReportID)
+		return nil, err
+	}
+	return &soldReportResolver{rep: rep}, nil
+}
+
+// Reports resolves the "reports" query.
+func (r *graphqlResolver) Reports(ctx context.Context, args struct {
+	Limit *int32
+	Skip  *int32
+}) ([]*soldReportResolver, error) {
+	params := report.HistoryParams{}
+	if args.Limit != nil {
+		params.Limit = int64(*args.Limit)
+	}
+	if args.Skip != nil {
+		params.Skip = int64(*args.Skip)
+	}
+
+	reports, err := report.ListReports(ctx, params, r.reportColl)
+	if err != nil {
+		err = errors.Wrap(err, "GraphQL: Error listing reports")
+		r.logger.E(tlog.Entry{
+			Description: err.Error(),
+			ErrorCode:   1,
+		}, params)
+		return nil, err
+	}
+
+	resolvers := make([]*soldReportResolver, len(reports))
+	for i := range reports {
+		resolvers[i] = &soldReportResolver{rep: &reports[i]}
+	}
+	return resolvers, nil
+}
+
+type soldItemsResolver struct {
+	rows      []report.ReportResult
+	rowErrors []report.RowError
+}
+
+func (r *soldItemsResolver) Rows() []*reportRowResolver {
+	return reportRowResolvers(r.rows)
+}
+
+func (r *soldItemsResolver) RowErrors() []*rowErrorResolver {
+	resolvers := make([]*rowErrorResolver, len(r.rowErrors))
+	for i, rowErr := range r.rowErrors {
+		resolvers[i] = &rowErrorResolver{rowErr: rowErr}
+	}
+	return resolvers
+}
+
+type soldReportResolver struct {
+	rep *report.SoldReport
+}
+
+func (r *soldReportResolver) ReportID() graphql.ID {
+	return graphql.ID(r.rep.ReportID.String())
+}
+
+func (r *soldReportResolver) Timestamp() float64 {
+	return float64(r.rep.Timestamp)
+}
+
+func (r *soldReportResolver) SearchQuery() *searchQueryResolver {
+	return &searchQueryResolver{params: r.rep.SearchQuery}
+}
+
+func (r *soldReportResolver) Empty() bool {
+	return r.rep.Empty
+}
+
+func (r *soldReportResolver) Rows() []*reportRowResolver {
+	return reportRowResolvers(r.rep.ReportResult)
+}
+
+func (r *soldReportResolver) CSV(args struct {
+	Columns  *[]string
+	Locale   *string
+	Decimals *int32
+	Comments *bool
+}) (string, error) {
+	opts := &report.CSVOptions{}
+	if args.Columns != nil {
+		opts.Columns = *args.Columns
+	}
+	if args.Locale != nil {
+		opts.Locale = *args.Locale
+	}
+	if args.Decimals != nil {
+		opts.Decimals = int(*args.Decimals)
+	}
+	if args.Comments != nil {
+		opts.Comments = *args.Comments
+	}
+
+	export, err := exportReport(r.rep, exportOptions{
+		Format: ExportFormatCSV,
+		CSV:    opts,
+	})
+	if err != nil {
+		err = errors.Wrap(err, "GraphQL: Error exporting report")
+		return "", err
+	}
+	return string(export), nil
+}
+
+type searchQueryResolver struct {
+	params report.SoldItemParams
+}
+
+func (r *searchQueryResolver) Timestamp() *rangeResolver {
+	if r.params.Timestamp == nil {
+		return nil
+	}
+	return &rangeResolver{comp: r.params.Timestamp}
+}
+
+func (r *searchQueryResolver) SKU() *string {
+	return comparatorString(r.params.SKU)
+}
+
+func (r *searchQueryResolver) Name() *string {
+	return comparatorString(r.params.Name)
+}
+
+func (r *searchQueryResolver) Lot() *string {
+	return comparatorString(r.params.Lot)
+}
+
+type rangeResolver struct {
+	comp *report.Comparator
+}
+
+func (r *rangeResolver) Gt() *float64 {
+	return optionalFloat(r.comp.Gt)
+}
+
+func (r *rangeResolver) Lt() *float64 {
+	return optionalFloat(r.comp.Lt)
+}
+
+type reportRowResolver struct {
+	row report.ReportResult
+}
+
+func reportRowResolvers(rows []report.ReportResult) []*reportRowResolver {
+	resolvers := make([]*reportRowResolver, len(rows))
+	for i, row := range rows {
+		resolvers[i] = &reportRowResolver{row: row}
+	}
+	return resolvers
+}
+
+func (r *reportRowResolver) SKU() *string {
+	return optionalString(r.row.SKU)
+}
+
+func (r *reportRowResolver) Name() *string {
+	return optionalString(r.row.Name)
+}
+
+func (r *reportRowResolver) Lot() *string {
+	return optionalString(r.row.Lot)
+}
+
+func (r *reportRowResolver) Bucket() *float64 {
+	return optionalFloat(float64(r.row.Bucket))
+}
+
+func (r *reportRowResolver) SoldWeight() float64 {
+	return r.row.SoldWeight
+}
+
+func (r *reportRowResolver) TotalWeight() float64 {
+	return r.row.TotalWeight
+}
+
+type rowErrorResolver struct {
+	rowErr report.RowError
+}
+
+func (r *rowErrorResolver) Index() int32 {
+	return int32(r.rowErr.Index)
+}
+
+func (r *rowErrorResolver) Error() string {
+	return r.rowErr.Error
+}
+
+// soldItemParamsFromGraphQL converts the GraphQL filter to search-params.
+func soldItemParamsFromGraphQL(in soldItemFilterInput) report.SoldItemParams {
+	params := report.SoldItemParams{
+		Timestamp: &report.Comparator{
+			Gt: in.Timestamp.Gt,
+			Lt: in.Timestamp.Lt,
+		},
+	}
+	if in.SKU != nil {
+		params.SKU = &report.Comparator{Eq: *in.SKU}
+	}
+	if in.Name != nil {
+		params.Name = &report.Comparator{Eq: *in.Name}
+	}
+	if in.Lot != nil {
+		params.Lot = &report.Comparator{Eq: *in.Lot}
+	}
+	return params
+}
+
+// aggregationSpecFromGraphQL converts the GraphQL aggregation to an
+// AggregationSpec. Enum-values are lowercased to match the report package's
+// dimensions, metrics and buckets.
+func aggregationSpecFromGraphQL(in *aggregationInput) report.AggregationSpec {
+	if in == nil {
+		return report.DefaultAggregation()
+	}
+
+	spec := report.AggregationSpec{}
+	if in.GroupBy != nil {
+		for _, dim := range *in.GroupBy {
+			spec.GroupBy = append(spec.GroupBy, strings.ToLower(dim))
+		}
+	}
+	if in.Metric != nil {
+		spec.Metric = strings.ToLower(*in.Metric)
+	}
+	if in.Bucket != nil {
+		spec.Bucket = strings.ToLower(*in.Bucket)
+	}
+	if in.SortBy != nil {
+		spec.SortBy = graphqlSortFields[*in.SortBy]
+	}
+	if in.Limit != nil {
+		spec.Limit = int64(*in.Limit)
+	}
+	return spec
+}
+
+func comparatorString(comp *report.Comparator) *string {
+	if comp == nil {
+		return nil
+	}
+	s, ok := comp.Eq.(string)
+	if !ok {
+		return nil
+	}
+	return &s
+}
+
+func optionalString(s string) *string {
+	if s == "" {
+		return nil
+	}
+	return &s
+}
+
+func optionalFloat(f float64) *float64 {
+	if f == 0 {
+		return nil
+	}
+	return &f
+}
@@ -0,0 +1,322 @@
+package main
+
+import (
+	"context"
+	"log"
+	"net"
+	"time"
+
+	"github.com/TerrexTech/agg-itemsoldflashsale-report/report"
+	"github.com/TerrexTech/agg-itemsoldflashsale-report/reportpb"
+	tlog "github.com/TerrexTech/go-logtransport/log"
+	"github.com/TerrexTech/go-mongoutils/mongo"
+	"github.com/pkg/errors"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+)
+
+// grpcChunkRows is the max number of report-rows sent per ReportChunk.
+const grpcChunkRows = 500
+
+// grpcServer implements reportpb.ReportServiceServer using the report package.
+type grpcServer struct {
+	logger       tlog.Logger
+	itemSoldColl *mongo.Collection
+	reportColl   *mongo.Collection
+	// timeout is the request-deadline, unless the client set an earlier one.
+	timeout time.Duration
+}
+
+// requestContext applies the request-deadline to the ctx of a call.
+func (s *grpcServer) requestContext(ctx context.Context) (context.Context, context.CancelFunc) {
+	return context.WithTimeout(ctx, s.timeout)
+}
+
+// GenerateReport generates and stores a new sold-item report, and streams
+// its rows in chunks.
+func (s *grpcServer) GenerateReport(
+	req *reportpb.GenerateReportRequest,
+	stream reportpb.ReportService_GenerateReportServer,
+) error {
+	filter := soldItemParamsFromPB(req.GetParams())
+	spec := aggregationSpecFromPB(req.GetParams().GetAggregation())
+	ctx, cancel := s.requestContext(stream.Context())
+	defer cancel()
+	result, errCode, err := generateReport(
+		ctx, s.logger, s.itemSoldColl, s.reportColl, filter, spec, nil,
+	)
+	if err != nil {
+		return status.Error(grpcCode(errCode), err.Error())
+	}
+	// The report-status is sent as trailer, since ReportChunk has no field
+	// for it.
+	stream.SetTrailer(metadata.Pairs("report-status", result.Status))
+
+	params := soldItemParamsToPB(filter)
+	params.Aggregation = req.GetParams().GetAggregation()
+	return sendReportChunks(stream, &reportpb.ReportChunk{
+		ReportId:  result.ReportID.String(),
+		Params:    params,
+		Timestamp: time.Now().Unix(),
+		RowErrors: rowErrorsToPB(result.RowErrors),
+	}, result.ReportResult)
+}
+
+// GetReport streams a stored report in chunks.
+func (s *grpcServer) GetReport(
+	req *reportpb.GetReportRequest,
+	stream reportpb.ReportService_GetReportServer,
+) error {
+	ctx, cancel := s.requestContext(stream.Context())
+	defer cancel()
+	rep, errCode, err := findRequestedReport(ctx, s.reportColl, reportRequest{
+		ReportID: req.GetReportId(),
+	})
+	if err != nil {
+		return status.Error(grpcCode(errCode), err.Error())
+	}
+
+	return sendReportChunks(stream, &reportpb.ReportChunk{
+		ReportId:  rep.ReportID.String(),
+		Params:    soldItemParamsToPB(rep.SearchQuery),
+		Timestamp: rep.Timestamp,
+	}, rep.ReportResult)
+}
+
+// ListReports lists the stored reports, newest first, without their rows.
+func (s *grpcServer) ListReports(
+	ctx context.Context,
+	req *reportpb.ListReportsRequest,
+) (*reportpb.ListReportsResponse, error) {
+	ctx, cancel := s.requestContext(ctx)
+	defer cancel()
+	reports, err := report.ListReports(ctx, report.HistoryParams{
+		Limit: req.GetLimit(),
+		Skip:  req.GetSkip(),
+	}, s.reportColl)
+	if err != nil {
+		return nil, status.Error(grpcCode(contextErrorCode(err, DatabaseError)), err.Error())
+	}
+
+	resp := &reportpb.ListReportsResponse{}
+	for _, rep := range reports {
+		resp.Reports = append(resp.Reports, &reportpb.ReportSummary{
+			ReportId:  rep.ReportID.String(),
+			Params:    soldItemParamsToPB(rep.SearchQuery),
+			Timestamp: rep.Timestamp,
+			RowCount:  int32(len(rep.ReportResult)),
+		})
+	}
+	return resp, nil
+}
+
+// ExportReport renders a stored report in the requested format.
+func (s *grpcServer) ExportReport(
+	ctx context.Context,
+	req *reportpb.ExportReportRequest,
+) (*reportpb.ExportReportResponse, error) {
+	reportReq := reportRequest{
+		ReportID: req.GetReportId(),
+	}
+	reportReq.Format = req.GetFormat()
+	if req.GetCsv() != nil {
+		reportReq.CSV = &report.CSVOptions{
+			Columns:  req.GetCsv().GetColumns(),
+			Locale:   req.GetCsv().GetLocale(),
+			Decimals: int(req.GetCsv().GetDecimals()),
+			Comments: req.GetCsv().GetComments(),
+		}
+	}
+	err := reportReq.exportOptions.validate()
+	if err != nil {
+		return nil, status.Error(grpcCode(ValidationError), err.Error())
+	}
+
+	ctx, cancel := s.requestContext(ctx)
+	defer cancel()
+	rep, errCode, err := findRequestedReport(ctx, s.reportColl, reportReq)
+	if err != nil {
+		return nil, status.Error(grpcCode(errCode), err.Error())
+	}
+
+	export, err := exportReport(rep, reportReq.exportOptions)
+	if err != nil {
+		return nil, status.Error(grpcCode(InternalError), err.Error())
+	}
+	return &reportpb.ExportReportResponse{
+		Data:        export,
+		ContentType: exportContentType(reportReq.Format),
+	}, nil
+}
+
+// chunkSender is implemented by the server-streams of ReportService.
+type chunkSender interface {
+	Send(*reportpb.ReportChunk) error
+}
+
+// sendReportChunks sends the rows in chunks of grpcChunkRows. The first
+// chunk is sent with the fields set in first. At least one chunk is sent,
+// and the final chunk has Last set.
+func sendReportChunks(
+	stream chunkSender,
+	first *reportpb.ReportChunk,
+	rows []report.ReportResult,
+) error {
+	chunk := first
+	seq := int32(0)
+	for {
+		end := grpcChunkRows
+		if end > len(rows) {
+			end = len(rows)
+		}
+		chunk.Rows = reportResultsToPB(rows[:end])
+		chunk.Sequence = seq
+		chunk.Last = end == len(rows)
+		rows = rows[end:]
+
+		err := stream.Send(chunk)
+		if err != nil {
+			err = errors.Wrapf(err, "Error sending report-chunk %d", seq)
+			log.Println(err)
+			return err
+		}
+		if chunk.Last {
+			return nil
+		}
+
+		seq++
+		chunk = &reportpb.ReportChunk{
+			ReportId: first.ReportId,
+		}
+	}
+}
+
+// grpcCode maps response error-codes to gRPC status-codes.
+func grpcCode(errorCode int16) codes.Code {
+	switch errorCode {
+	case DatabaseError:
+		return codes.Unavailable
+	case JobCancelledError:
+		return codes.Canceled
+	case TimeoutError:
+		return codes.DeadlineExceeded
+	case ValidationError:
+		return codes.InvalidArgument
+	case NotFoundError:
+		return codes.NotFound
+	case UnauthorizedError:
+		return codes.PermissionDenied
+	case PayloadTooLargeError:
+		return codes.ResourceExhausted
+	default:
+		return codes.Internal
+	}
+}
+
+func soldItemParamsFromPB(params *reportpb.SoldItemParams) report.SoldItemParams {
+	filter := report.SoldItemParams{}
+	if params.GetTimestamp() != nil {
+		filter.Timestamp = &report.Comparator{
+			Lt: params.GetTimestamp().GetLt(),
+			Gt: params.GetTimestamp().GetGt(),
+		}
+	}
+	if params.GetSku() != "" {
+		filter.SKU = &report.Comparator{Eq: params.GetSku()}
+	}
+	if params.GetName() != "" {
+		filter.Name = &report.Comparator{Eq: params.GetName()}
+	}
+	if params.GetLot() != "" {
+		filter.Lot = &report.Comparator{Eq: params.GetLot()}
+	}
+	return filter
+}
+
+// aggregationSpecFromPB converts the aggregation, and returns the default
+// aggregation if it isn't set.
+func aggregationSpecFromPB(spec *reportpb.AggregationSpec) report.AggregationSpec {
+	if spec == nil {
+		return report.DefaultAggregation()
+	}
+	return report.AggregationSpec{
+		GroupBy: spec.GetGroupBy(),
+		Metric:  spec.GetMetric(),
+		Bucket:  spec.GetBucket(),
+		SortBy:  spec.GetSortBy(),
+		Limit:   spec.GetLimit(),
+	}
+}
+
+func soldItemParamsToPB(filter report.SoldItemParams) *reportpb.SoldItemParams {
+	params := &reportpb.SoldItemParams{}
+	if filter.Timestamp != nil {
+		params.Timestamp = &reportpb.Comparator{
+			Lt: filter.Timestamp.Lt,
+			Gt: filter.Timestamp.Gt,
+		}
+	}
+	if sku := comparatorString(filter.SKU); sku != nil {
+		params.Sku = *sku
+	}
+	if name := comparatorString(filter.Name); name != nil {
+		params.Name = *name
+	}
+	if lot := comparatorString(filter.Lot); lot != nil {
+		params.Lot = *lot
+	}
+	return params
+}
+
+func reportResultsToPB(rows []report.ReportResult) []*reportpb.ReportResult {
+	pbRows := make([]*reportpb.ReportResult, 0, len(rows))
+	for _, row := range rows {
+		pbRows = append(pbRows, &reportpb.ReportResult{
+			Sku:         row.SKU,
+			Name:        row.Name,
+			Lot:         row.Lot,
+			Bucket:      row.Bucket,
+			SoldWeight:  row.SoldWeight,
+			TotalWeight: row.TotalWeight,
+		})
+	}
+	return pbRows
+}
+
+func rowErrorsToPB(rowErrors []report.RowError) []*reportpb.RowError {
+	pbErrors := make([]*reportpb.RowError, 0, len(rowErrors))
+	for _, rowErr := range rowErrors {
+		pbErrors = append(pbErrors, &reportpb.RowError{
+			Index: int32(rowErr.Index),
+			Error: rowErr.Error,
+		})
+	}
+	return pbErrors
+}
+
+// startGRPCServer serves the ReportService on addr in background.
+func startGRPCServer(addr string, srv reportpb.ReportServiceServer) (*grpc.Server, error) {
+	lis, err := net.Listen("tcp", addr)
+	if err != nil {
+		err = errors.Wrapf(err, "Error listening on %s", addr)
+		return nil, err
+	}
+
+	server := grpc.NewServer(
+		grpc.UnaryInterceptor(grpcRecoverUnary),
+		grpc.StreamInterceptor(grpcRecoverStream),
+	)
+	reportpb.RegisterReportServiceServer(server, srv)
+
+	go func() {
+		log.Printf("Serving gRPC on %s", addr)
+		err := server.Serve(lis)
+		if err != nil {
+			err = errors.Wrap(err, "gRPC server stopped")
+			log.Println(err)
+		}
+	}()
+	return server, nil
+}
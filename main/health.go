@@ -0,0 +1,349 @@
+package main
+
+import (
+	"encoding/json"
+	"log"
+	"net/http"
+	"os"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/Shopify/sarama"
+	"github.com/TerrexTech/go-commonutils/commonutil"
+	"github.com/TerrexTech/go-eventspoll/poll"
+	"github.com/TerrexTech/go-mongoutils/mongo"
+	"github.com/pkg/errors"
+)
+
+// defaultHealthListenAddr is where the health-endpoints are served, if
+// HEALTH_LISTEN_ADDR is not set.
+const defaultHealthListenAddr = ":8081"
+
+// readinessCheckTimeout bounds each readiness-check, so probes don't hang
+// while a dependency is unresponsive.
+const readinessCheckTimeout = 2 * time.Second
+
+// HealthMonitor serves the liveness, readiness and status endpoints. It is
+// started before the dependencies are connected, and reports not-ready
+// until they are set using SetDependencies, and again once Drain is called.
+type HealthMonitor struct {
+	lock       sync.RWMutex
+	eventPoll  poll.EventPoll
+	reportColl *mongo.Collection
+	workers    *WorkerPool
+	jobs       *JobManager
+	draining   bool
+
+	kafkaBrokers  []string
+	consumerGroup string
+	kafkaClient   sarama.Client
+
+	startedAt time.Time
+	// lastEventAt is the unix-nano time the last event was handled.
+	lastEventAt int64
+}
+
+// healthCheck is the result of a readiness-check. Error is blank if the
+// check passed.
+type healthCheck struct {
+	Name  string `json:"name"`
+	OK    bool   `json:"ok"`
+	Error string `json:"error,omitempty"`
+}
+
+// readiness is the response of /readyz.
+type readiness struct {
+	Ready  bool          `json:"ready"`
+	Checks []healthCheck `json:"checks"`
+}
+
+// serviceStatus is the response of /status.
+type serviceStatus struct {
+	StartedAt int64 `json:"startedAt"`
+	// LastEventAt is when the last event was handled, as unix-timestamp,
+	// or 0 if no event was handled yet.
+	LastEventAt     int64 `json:"lastEventAt"`
+	QueueDepth      int64 `json:"queueDepth"`
+	EventsInFlight  int64 `json:"eventsInFlight"`
+	ReportsInFlight int   `json:"reportsInFlight"`
+	Draining        bool  `json:"draining"`
+}
+
+// NewHealthMonitor creates a HealthMonitor for the service's Kafka brokers
+// and query consumer-group.
+func NewHealthMonitor() *HealthMonitor {
+	return &HealthMonitor{
+		kafkaBrokers:  *commonutil.ParseHosts(os.Getenv("KAFKA_BROKERS")),
+		consumerGroup: os.Getenv("KAFKA_CONSUMER_EVENT_QUERY_GROUP"),
+		startedAt:     time.Now(),
+	}
+}
+
+// SetDependencies sets the clients checked for readiness, and the
+// worker-pool and job-manager reported in the status.
+func (h *HealthMonitor) SetDependencies(
+	eventPoll poll.EventPoll,
+	reportColl *mongo.Collection,
+	workers *WorkerPool,
+	jobs *JobManager,
+) {
+	h.lock.Lock()
+	defer h.lock.Unlock()
+
+	h.eventPoll = eventPoll
+	h.reportColl = reportColl
+	h.workers = workers
+	h.jobs = jobs
+}
+
+// Drain marks the service not-ready, such as when shutting down.
+func (h *HealthMonitor) Drain() {
+	h.lock.Lock()
+	defer h.lock.Unlock()
+	h.draining = true
+}
+
+// EventHandled records the time an event was handled.
+func (h *HealthMonitor) EventHandled() {
+	atomic.StoreInt64(&h.lastEventAt, time.Now().UnixNano())
+}
+
+// Close closes the Kafka client used for readiness-checks.
+func (h *HealthMonitor) Close() error {
+	h.lock.Lock()
+	defer h.lock.Unlock()
+
+	if h.kafkaClient == nil {
+		return nil
+	}
+	err := h.kafkaClient.Close()
+	h.kafkaClient = nil
+	return err
+}
+
+// Handler returns the http.Handler serving /healthz, /readyz and /status.
+func (h *HealthMonitor) Handler() http.Handler {
+	mux := http.NewServeMux()
+	// The process is alive as long as it can serve requests
+	mux.HandleFunc("/healthz", func(w http.ResponseWriter, r *http.Request) {
+		writeHealthJSON(w, http.StatusOK, map[string]string{"status": "ok"})
+	})
+	mux.HandleFunc("/readyz", func(w http.ResponseWriter, r *http.Request) {
+		ready := h.readiness()
+		status := http.StatusOK
+		if !ready.Ready {
+			status = http.StatusServiceUnavailable
+		}
+		writeHealthJSON(w, status, ready)
+	})
+	mux.HandleFunc("/status", func(w http.ResponseWriter, r *http.Request) {
+		writeHealthJSON(w, http.StatusOK, h.status())
+	})
+	return mux
+}
+
+func (h *HealthMonitor) readiness() readiness {
+	h.lock.RLock()
+	eventPoll := h.eventPoll
+	reportColl := h.reportColl
+	draining := h.draining
+	h.lock.RUnlock()
+
+	if draining {
+		return readiness{
+			Checks: []healthCheck{
+				newHealthCheck("shutdown", errors.New("service is shutting down")),
+			},
+		}
+	}
+	if eventPoll == nil {
+		return readiness{
+			Checks: []healthCheck{
+				newHealthCheck("startup", errors.New("dependencies are not connected yet")),
+			},
+		}
+	}
+
+	checks := []healthCheck{
+		newHealthCheck("mongo", withTimeout(readinessCheckTimeout, func() error {
+			return pingCollection(reportColl)
+		})),
+		newHealthCheck("kafkaConsumerGroup", withTimeout(readinessCheckTimeout, h.checkConsumerGroup)),
+		newHealthCheck("eventPoll", errors.Wrap(
+			eventPoll.RoutinesCtx().Err(), "event-poll context closed",
+		)),
+	}
+	ready := readiness{
+		Ready:  true,
+		Checks: checks,
+	}
+	for _, check := range checks {
+		if !check.OK {
+			ready.Ready = false
+		}
+	}
+	return ready
+}
+
+// checkConsumerGroup checks that the query consumer-group is stable and
+// has members. Other instances of the service may be the members, since
+// the group doesn't identify its members by instance.
+func (h *HealthMonitor) checkConsumerGroup() error {
+	kafkaClient, err := h.getKafkaClient()
+	if err != nil {
+		return err
+	}
+	coordinator, err := kafkaClient.Coordinator(h.consumerGroup)
+	if err != nil {
+		return errors.Wrap(err, "Error getting consumer-group coordinator")
+	}
+	resp, err := coordinator.DescribeGroups(&sarama.DescribeGroupsRequest{
+		Groups: []string{h.consumerGroup},
+	})
+	if err != nil {
+		return errors.Wrap(err, "Error describing consumer-group")
+	}
+	if len(resp.Groups) == 0 {
+		return errors.Errorf("consumer-group %s not found", h.consumerGroup)
+	}
+
+	group := resp.Groups[0]
+	if group.Err != sarama.ErrNoError {
+		return errors.Wrapf(group.Err, "Error describing consumer-group %s", h.consumerGroup)
+	}
+	if group.State != "Stable" || len(group.Members) == 0 {
+		return errors.Errorf(
+			"consumer-group %s not joined: state %s with %d members",
+			h.consumerGroup, group.State, len(group.Members),
+		)
+	}
+	return nil
+}
+
+// getKafkaClient returns the Kafka client, which is created on first use
+// and reused for later checks. The client connects without holding the
+// lock, so unreachable brokers don't block /status and Drain. Its timeouts
+// are shorter than readinessCheckTimeout, so timed-out checks don't leave
+// connection-attempts running long after.
+func (h *HealthMonitor) getKafkaClient() (sarama.Client, error) {
+	h.lock.RLock()
+	kafkaClient := h.kafkaClient
+	h.lock.RUnlock()
+	if kafkaClient != nil {
+		return kafkaClient, nil
+	}
+
+	config := sarama.NewConfig()
+	config.Version = sarama.V2_0_0_0
+	config.Net.DialTimeout = readinessCheckTimeout / 2
+	config.Net.ReadTimeout = readinessCheckTimeout / 2
+	config.Net.WriteTimeout = readinessCheckTimeout / 2
+	config.Metadata.Retry.Max = 1
+	config.Metadata.Retry.Backoff = 100 * time.Millisecond
+	kafkaClient, err := sarama.NewClient(h.kafkaBrokers, config)
+	if err != nil {
+		return nil, errors.Wrap(err, "Error connecting to Kafka")
+	}
+
+	h.lock.Lock()
+	defer h.lock.Unlock()
+	// Another check may have connected meanwhile, whose client is kept
+	if h.kafkaClient != nil {
+		kafkaClient.Close()
+		return h.kafkaClient, nil
+	}
+	h.kafkaClient = kafkaClient
+	return kafkaClient, nil
+}
+
+func (h *HealthMonitor) status() serviceStatus {
+	h.lock.RLock()
+	defer h.lock.RUnlock()
+
+	status := serviceStatus{
+		StartedAt: h.startedAt.Unix(),
+		Draining:  h.draining,
+	}
+	lastEventAt := atomic.LoadInt64(&h.lastEventAt)
+	if lastEventAt != 0 {
+		status.LastEventAt = time.Unix(0, lastEventAt).Unix()
+	}
+	if h.workers != nil {
+		status.QueueDepth = h.workers.Queued()
+		status.EventsInFlight = h.workers.InFlight()
+	}
+	if h.jobs != nil {
+		status.ReportsInFlight = h.jobs.Running()
+	}
+	return status
+}
+
+func newHealthCheck(name string, err error) healthCheck {
+	check := healthCheck{
+		Name: name,
+		OK:   err == nil,
+	}
+	if err != nil {
+		check.Error = err.Error()
+	}
+	return check
+}
+
+// withTimeout runs fn, and returns an error if it doesn't return within
+// the timeout. The check keeps running in background in that case.
+func withTimeout(timeout time.Duration, fn func() error) error {
+	done := make(chan error, 1)
+	go func() {
+		done <- fn()
+	}()
+
+	select {
+	case err := <-done:
+		return err
+	case <-time.After(timeout):
+		return errors.Errorf("check timed out after %s", timeout)
+	}
+}
+
+func writeHealthJSON(w http.ResponseWriter, status int, v interface{}) {
+	body, err := json.Marshal(v)
+	if err != nil {
+		err = errors.Wrap(err, "Error marshalling health-response")
+		log.Println(err)
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	_, err = w.Write(body)
+	if err != nil {
+		err = errors.Wrap(err, "Error writing health-response")
+		log.Println(err)
+	}
+}
+
+// startHealthServer serves the HealthMonitor on HEALTH_LISTEN_ADDR.
+func startHealthServer(monitor *HealthMonitor) *http.Server {
+	addr := os.Getenv("HEALTH_LISTEN_ADDR")
+	if addr == "" {
+		addr = defaultHealthListenAddr
+	}
+	server := &http.Server{
+		Addr:         addr,
+		Handler:      monitor.Handler(),
+		ReadTimeout:  5 * time.Second,
+		WriteTimeout: 10 * time.Second,
+	}
+
+	go func() {
+		log.Printf("Serving health-endpoints on %s", addr)
+		err := server.ListenAndServe()
+		if err != nil && err != http.ErrServerClosed {
+			err = errors.Wrap(err, "Health server stopped")
+			log.Println(err)
+		}
+	}()
+	return server
+}
@@ -0,0 +1,438 @@
+package main
+
+import (
+	"encoding/json"
+	"io"
+	"io/ioutil"
+	"log"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/TerrexTech/agg-itemsoldflashsale-report/report"
+	"github.com/TerrexTech/go-eventstore-models/model"
+	"github.com/TerrexTech/uuuid"
+	"github.com/pkg/errors"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+)
+
+// maxHTTPBodyBytes limits the size of HTTP request-bodies.
+const maxHTTPBodyBytes = 1 << 20
+
+// httpError is the response-body for failed HTTP requests.
+type httpError struct {
+	Error     string              `json:"error"`
+	ErrorCode int16               `json:"errorCode"`
+	Details   report.ErrorDetails `json:"details"`
+}
+
+// httpHandler serves the sold-item reports over HTTP/JSON. Requests are
+// converted to query-events and run through the same Dispatcher as events
+// from Kafka, so both transports behave identically.
+//
+// Routes:
+//
+//	POST   /reports                Run sold-item report (body: SoldItemSummary event-data)
+//	POST   /reports/batch          Run sub-queries (body: SoldItemBatch event-data)
+//	GET    /reports?limit=&skip=   List stored reports, newest first
+//	GET    /reports/{reportID}     Get stored report, "?format=" exports it as
+//	                               csv, xlsx or html
+//	                               (CSV: "&columns=&locale=&decimals=&comments=")
+//	GET    /reports/{reportID}/chart
+//	                               Render chart-image of stored report
+//	                               ("?type=bar|line&format=svg|png&width=&height=&series=")
+//	POST   /reports/{reportID}/chart
+//	                               Render chart-image, and store it alongside the report
+//	GET    /jobs/{jobID}           Get status of async report-job
+//	DELETE /jobs/{jobID}           Cancel async report-job
+//	POST   /graphql                GraphQL queries, if the schema was loaded
+//	GET    /metrics                Prometheus metrics
+type httpHandler struct {
+	dispatcher *Dispatcher
+	mux        *http.ServeMux
+}
+
+// newHTTPHandler creates the http.Handler for the report-routes.
+func newHTTPHandler(dispatcher *Dispatcher) *httpHandler {
+	h := &httpHandler{
+		dispatcher: dispatcher,
+		mux:        http.NewServeMux(),
+	}
+	h.mux.HandleFunc("/reports", h.reports)
+	h.mux.HandleFunc("/reports/", h.reportByID)
+	h.mux.HandleFunc("/jobs/", h.jobByID)
+	h.mux.Handle("/metrics", promhttp.Handler())
+	return h
+}
+
+func (h *httpHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	h.mux.ServeHTTP(w, r)
+}
+
+// reports handles "/reports".
+func (h *httpHandler) reports(w http.ResponseWriter, r *http.Request) {
+	switch r.Method {
+	case http.MethodGet:
+		params := report.HistoryParams{}
+		var err error
+		query := r.URL.Query()
+		if query.Get("limit") != "" {
+			params.Limit, err = strconv.ParseInt(query.Get("limit"), 10, 64)
+			if err != nil {
+				err = invalidField("limit", "%s", err.Error())
+			}
+		}
+		if err == nil && query.Get("skip") != "" {
+			params.Skip, err = strconv.ParseInt(query.Get("skip"), 10, 64)
+			if err != nil {
+				err = invalidField("skip", "%s", err.Error())
+			}
+		}
+		if err != nil {
+			err = errors.Wrap(err, "Error parsing paging-parameters")
+			writeHTTPError(w, http.StatusBadRequest, err, ValidationError)
+			return
+		}
+		h.dispatch(w, r, ReportHistoryAction, params)
+
+	case http.MethodPost:
+		h.dispatchBody(w, r, SoldItemSummaryAction)
+
+	default:
+		writeMethodNotAllowed(w, http.MethodGet, http.MethodPost)
+	}
+}
+
+// reportByID handles "/reports/{reportID}", "/reports/{reportID}/chart"
+// and "/reports/batch".
+func (h *httpHandler) reportByID(w http.ResponseWriter, r *http.Request) {
+	reportID := strings.TrimPrefix(r.URL.Path, "/reports/")
+
+	if reportID == "batch" {
+		if r.Method != http.MethodPost {
+			writeMethodNotAllowed(w, http.MethodPost)
+			return
+		}
+		h.dispatchBody(w, r, SoldItemBatchAction)
+		return
+	}
+	if strings.HasSuffix(reportID, "/chart") {
+		h.reportChart(w, r, strings.TrimSuffix(reportID, "/chart"))
+		return
+	}
+
+	if r.Method != http.MethodGet {
+		writeMethodNotAllowed(w, http.MethodGet)
+		return
+	}
+	query := r.URL.Query()
+	req := reportRequest{
+		ReportID: reportID,
+	}
+	req.Format = query.Get("format")
+	if req.Format == "" {
+		h.dispatch(w, r, ReportLookupAction, req)
+		return
+	}
+	if req.Format == ExportFormatCSV {
+		csvOpts, err := csvOptionsFromQuery(query)
+		if err != nil {
+			writeHTTPError(w, http.StatusBadRequest, err, ValidationError)
+			return
+		}
+		req.CSV = csvOpts
+	}
+	h.dispatchExport(w, r, ReportExportAction, req, exportContentType(req.Format))
+}
+
+// reportChart handles "/reports/{reportID}/chart". GET renders the chart,
+// POST also stores it alongside the report.
+func (h *httpHandler) reportChart(w http.ResponseWriter, r *http.Request, reportID string) {
+	if r.Method != http.MethodGet && r.Method != http.MethodPost {
+		writeMethodNotAllowed(w, http.MethodGet, http.MethodPost)
+		return
+	}
+	opts, err := chartOptionsFromQuery(r.URL.Query())
+	if err != nil {
+		writeHTTPError(w, http.StatusBadRequest, err, ValidationError)
+		return
+	}
+	req := chartRequest{
+		ReportID: reportID,
+		Chart:    *opts,
+		Store:    r.Method == http.MethodPost,
+	}
+	h.dispatchExport(w, r, ReportChartAction, req, report.ChartContentType(opts.Format))
+}
+
+// chartOptionsFromQuery parses the "type", "format", "width", "height" and
+// "series" query-params.
+func chartOptionsFromQuery(query url.Values) (*report.ChartOptions, error) {
+	opts := &report.ChartOptions{
+		Type:   query.Get("type"),
+		Format: query.Get("format"),
+	}
+	ints := []struct {
+		param string
+		value *int
+	}{
+		{"width", &opts.Width},
+		{"height", &opts.Height},
+		{"series", &opts.Series},
+	}
+	for _, i := range ints {
+		if query.Get(i.param) == "" {
+			continue
+		}
+		var err error
+		*i.value, err = strconv.Atoi(query.Get(i.param))
+		if err != nil {
+			err = invalidField(i.param, "%s", err.Error())
+			return nil, errors.Wrapf(err, "Error parsing %s", i.param)
+		}
+	}
+	return opts, opts.Validate()
+}
+
+// csvOptionsFromQuery parses the "columns", "locale", "decimals" and
+// "comments" query-params.
+func csvOptionsFromQuery(query url.Values) (*report.CSVOptions, error) {
+	opts := &report.CSVOptions{
+		Locale: query.Get("locale"),
+	}
+	if query.Get("columns") != "" {
+		opts.Columns = strings.Split(query.Get("columns"), ",")
+	}
+	var err error
+	if query.Get("decimals") != "" {
+		opts.Decimals, err = strconv.Atoi(query.Get("decimals"))
+		if err != nil {
+			err = invalidField("decimals", "%s", err.Error())
+			return nil, errors.Wrap(err, "Error parsing decimals")
+		}
+	}
+	if query.Get("comments") != "" {
+		opts.Comments, err = strconv.ParseBool(query.Get("comments"))
+		if err != nil {
+			err = invalidField("comments", "%s", err.Error())
+			return nil, errors.Wrap(err, "Error parsing comments")
+		}
+	}
+	return opts, nil
+}
+
+// jobByID handles "/jobs/{jobID}".
+func (h *httpHandler) jobByID(w http.ResponseWriter, r *http.Request) {
+	req := jobRequest{
+		JobID: strings.TrimPrefix(r.URL.Path, "/jobs/"),
+	}
+	switch r.Method {
+	case http.MethodGet:
+		h.dispatch(w, r, JobStatusAction, req)
+	case http.MethodDelete:
+		h.dispatch(w, r, JobCancelAction, req)
+	default:
+		writeMethodNotAllowed(w, http.MethodGet, http.MethodDelete)
+	}
+}
+
+// dispatchBody dispatches an event with the request-body as event-data.
+func (h *httpHandler) dispatchBody(w http.ResponseWriter, r *http.Request, action string) {
+	// Reads one byte more than allowed, to detect bodies that are too large
+	body, err := ioutil.ReadAll(io.LimitReader(r.Body, maxHTTPBodyBytes+1))
+	if err != nil {
+		err = errors.Wrap(err, "Error reading request-body")
+		writeHTTPError(w, http.StatusBadRequest, err, ValidationError)
+		return
+	}
+	if len(body) > maxHTTPBodyBytes {
+		err = errors.Errorf("request-body exceeds %d bytes", maxHTTPBodyBytes)
+		writeHTTPError(w, http.StatusRequestEntityTooLarge, err, PayloadTooLargeError)
+		return
+	}
+
+	// Bodies of report-requests can select the export-format, the result
+	// of all other bodies is JSON
+	opts := exportOptions{}
+	_ = json.Unmarshal(body, &opts)
+	h.dispatchData(w, r, action, body, exportContentType(opts.Format))
+}
+
+// dispatch dispatches an event with the JSON-marshalled data as event-data.
+func (h *httpHandler) dispatch(
+	w http.ResponseWriter,
+	r *http.Request,
+	action string,
+	data interface{},
+) {
+	h.dispatchExport(w, r, action, data, "application/json")
+}
+
+// dispatchExport is like dispatch, but the result is written with the
+// specified Content-Type.
+func (h *httpHandler) dispatchExport(
+	w http.ResponseWriter,
+	r *http.Request,
+	action string,
+	data interface{},
+	contentType string,
+) {
+	dataMarshal, err := json.Marshal(data)
+	if err != nil {
+		err = errors.Wrap(err, "Error marshalling event-data")
+		writeHTTPError(w, http.StatusInternalServerError, err, InternalError)
+		return
+	}
+	h.dispatchData(w, r, action, dataMarshal, contentType)
+}
+
+func (h *httpHandler) dispatchData(
+	w http.ResponseWriter,
+	r *http.Request,
+	action string,
+	data []byte,
+	contentType string,
+) {
+	event, err := newHTTPEvent(action, data)
+	if err != nil {
+		writeHTTPError(w, http.StatusInternalServerError, err, InternalError)
+		return
+	}
+
+	resp := h.dispatcher.Dispatch(r.Context(), event)
+	if resp == nil {
+		err = errors.New("no response")
+		writeHTTPError(w, http.StatusInternalServerError, err, InternalError)
+		return
+	}
+	if resp.Error != "" {
+		writeResponseError(w, resp)
+		return
+	}
+
+	w.Header().Set("Content-Type", contentType)
+	w.WriteHeader(http.StatusOK)
+	_, err = w.Write(resp.Result)
+	if err != nil {
+		err = errors.Wrap(err, "Error writing HTTP response")
+		log.Println(err)
+	}
+}
+
+// newHTTPEvent creates a query-event for an HTTP request.
+func newHTTPEvent(action string, data []byte) (*model.Event, error) {
+	eventID, err := uuuid.NewV4()
+	if err != nil {
+		err = errors.Wrap(err, "Error generating event-UUID")
+		return nil, err
+	}
+	correlationID, err := uuuid.NewV4()
+	if err != nil {
+		err = errors.Wrap(err, "Error generating CorrelationID")
+		return nil, err
+	}
+
+	return &model.Event{
+		AggregateID:   aggregateID,
+		CorrelationID: correlationID,
+		Data:          data,
+		EventAction:   "query",
+		NanoTime:      time.Now().UnixNano(),
+		ServiceAction: action,
+		UUID:          eventID,
+	}, nil
+}
+
+// httpStatus maps response error-codes to HTTP status-codes.
+func httpStatus(errorCode int16) int {
+	switch errorCode {
+	case UnknownActionError:
+		return http.StatusNotFound
+	case DatabaseError:
+		return http.StatusBadGateway
+	case JobCancelledError:
+		return http.StatusConflict
+	case TimeoutError:
+		return http.StatusGatewayTimeout
+	case ValidationError:
+		return http.StatusBadRequest
+	case NotFoundError:
+		return http.StatusNotFound
+	case UnauthorizedError:
+		return http.StatusUnauthorized
+	case PayloadTooLargeError:
+		return http.StatusRequestEntityTooLarge
+	default:
+		return http.StatusInternalServerError
+	}
+}
+
+// writeMethodNotAllowed rejects the request-method, and lists the allowed
+// methods in the Allow-header.
+func writeMethodNotAllowed(w http.ResponseWriter, allowed ...string) {
+	w.Header().Set("Allow", strings.Join(allowed, ", "))
+	err := errors.Errorf("method not allowed, use %s", strings.Join(allowed, " or "))
+	writeHTTPError(w, http.StatusMethodNotAllowed, err, ValidationError)
+}
+
+// writeHTTPError writes the error with its error-details.
+func writeHTTPError(w http.ResponseWriter, status int, err error, errorCode int16) {
+	writeHTTPErrorBody(w, status, httpError{
+		Error:     err.Error(),
+		ErrorCode: errorCode,
+		Details:   errorDetails(err, errorCode),
+	})
+}
+
+// writeResponseError writes the error of the KafkaResponse, whose Result
+// contains the error-details.
+func writeResponseError(w http.ResponseWriter, resp *model.KafkaResponse) {
+	details := report.ErrorDetails{}
+	err := json.Unmarshal(resp.Result, &details)
+	if err != nil {
+		details = errorDetails(errors.New(resp.Error), resp.ErrorCode)
+	}
+	writeHTTPErrorBody(w, httpStatus(resp.ErrorCode), httpError{
+		Error:     resp.Error,
+		ErrorCode: resp.ErrorCode,
+		Details:   details,
+	})
+}
+
+func writeHTTPErrorBody(w http.ResponseWriter, status int, httpErr httpError) {
+	body, err := json.Marshal(httpErr)
+	if err != nil {
+		err = errors.Wrap(err, "Error marshalling HTTP error")
+		log.Println(err)
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	_, err = w.Write(body)
+	if err != nil {
+		err = errors.Wrap(err, "Error writing HTTP response")
+		log.Println(err)
+	}
+}
+
+// startHTTPServer serves the report-routes on addr in background.
+func startHTTPServer(addr string, handler http.Handler) *http.Server {
+	server := &http.Server{
+		Addr:         addr,
+		Handler:      handler,
+		ReadTimeout:  10 * time.Second,
+		WriteTimeout: 5 * time.Minute,
+	}
+
+	go func() {
+		log.Printf("Serving HTTP on %s", addr)
+		err := server.ListenAndServe()
+		if err != nil && err != http.ErrServerClosed {
+			err = errors.Wrap(err, "HTTP server stopped")
+			log.Println(err)
+		}
+	}()
+	return server
+}
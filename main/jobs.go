@@ -0,0 +1,380 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"log"
+	"sync"
+	"time"
+
+	"github.com/TerrexTech/agg-itemsoldflashsale-report/report"
+	"github.com/TerrexTech/go-eventstore-models/model"
+	"github.com/TerrexTech/uuuid"
+	"github.com/pkg/errors"
+)
+
+// JobProgressAction is the ServiceAction set on job acknowledgements and
+// progress-events. The final job-response keeps the ServiceAction of the
+// original event.
+const JobProgressAction = "JobProgress"
+
+// Job statuses.
+const (
+	JobQueued    = "queued"
+	JobRunning   = "running"
+	JobCompleted = "completed"
+	JobFailed    = "failed"
+	JobCancelled = "cancelled"
+)
+
+// jobRetention is how long finished jobs can still be queried.
+const jobRetention = 1 * time.Hour
+
+// JobStatus is the queryable state of a report-job.
+type JobStatus struct {
+	JobID     uuuid.UUID `json:"jobID"`
+	Status    string     `json:"status"`
+	Stage     string     `json:"stage,omitempty"`
+	Progress  int        `json:"progress"`
+	ReportID  uuuid.UUID `json:"reportID,omitempty"`
+	Error     string     `json:"error,omitempty"`
+	CreatedAt int64      `json:"createdAt"`
+	UpdatedAt int64      `json:"updatedAt"`
+}
+
+// ProgressFunc reports the current stage of a job, and its completion
+// percentage.
+type ProgressFunc func(stage string, progress int)
+
+// JobFunc generates the report for a job. The ctx is cancelled when the
+// job is cancelled.
+type JobFunc func(ctx context.Context, progress ProgressFunc) (*report.QueryResult, int16, error)
+
+type job struct {
+	status JobStatus
+	event  model.Event
+	cancel context.CancelFunc
+}
+
+// JobManager runs report-jobs in background, and publishes their progress
+// and results as KafkaResponses. Jobs are only kept in memory, so their
+// status can only be queried from the instance that runs them, and is lost
+// on restart. Jobs still running on shutdown are cancelled.
+type JobManager struct {
+	lock      sync.RWMutex
+	jobs      map[uuuid.UUID]*job
+	responses chan<- *model.KafkaResponse
+	running   sync.WaitGroup
+
+	// publishLock is held while publishing on responses, which stops once
+	// stopped is set
+	publishLock sync.RWMutex
+	stopped     bool
+}
+
+// NewJobManager creates a JobManager which publishes job-responses on the
+// provided channel.
+func NewJobManager(responses chan<- *model.KafkaResponse) *JobManager {
+	return &JobManager{
+		jobs:      map[uuuid.UUID]*job{},
+		responses: responses,
+	}
+}
+
+// Submit starts a new job for the event, and returns its initial status.
+// The job fails with TimeoutError if it runs longer than timeout. Jobs
+// without a timeout run until they complete or are cancelled.
+func (m *JobManager) Submit(
+	event *model.Event,
+	timeout time.Duration,
+	run JobFunc,
+) (JobStatus, error) {
+	jobID, err := uuuid.NewV4()
+	if err != nil {
+		err = errors.Wrap(err, "Error generating jobID")
+		return JobStatus{}, err
+	}
+
+	var (
+		ctx    context.Context
+		cancel context.CancelFunc
+	)
+	if timeout > 0 {
+		ctx, cancel = context.WithTimeout(context.Background(), timeout)
+	} else {
+		ctx, cancel = context.WithCancel(context.Background())
+	}
+	now := time.Now().Unix()
+	j := &job{
+		status: JobStatus{
+			JobID:     jobID,
+			Status:    JobQueued,
+			CreatedAt: now,
+			UpdatedAt: now,
+		},
+		event:  *event,
+		cancel: cancel,
+	}
+
+	m.lock.Lock()
+	m.pruneFinished()
+	m.jobs[jobID] = j
+	status := j.status
+	m.lock.Unlock()
+
+	m.running.Add(1)
+	go m.runJob(ctx, j, run)
+	return status, nil
+}
+
+// Status returns the current status of the job.
+func (m *JobManager) Status(jobID uuuid.UUID) (JobStatus, bool) {
+	m.lock.RLock()
+	defer m.lock.RUnlock()
+
+	j, ok := m.jobs[jobID]
+	if !ok {
+		return JobStatus{}, false
+	}
+	return j.status, true
+}
+
+// Cancel cancels the job. Jobs that already finished are not affected.
+func (m *JobManager) Cancel(jobID uuuid.UUID) (JobStatus, bool) {
+	m.lock.Lock()
+	defer m.lock.Unlock()
+
+	j, ok := m.jobs[jobID]
+	if !ok {
+		return JobStatus{}, false
+	}
+	if !isJobFinished(j.status.Status) {
+		j.cancel()
+		j.status.Status = JobCancelled
+		j.status.UpdatedAt = time.Now().Unix()
+	}
+	return j.status, true
+}
+
+// Running returns the number of jobs that have not finished yet.
+func (m *JobManager) Running() int {
+	m.lock.RLock()
+	defer m.lock.RUnlock()
+
+	running := 0
+	for _, j := range m.jobs {
+		if !isJobFinished(j.status.Status) {
+			running++
+		}
+	}
+	return running
+}
+
+// Shutdown waits until all running jobs published their results. If ctx is
+// done first, the remaining jobs are cancelled, their final status is
+// published, and the ctx-error is returned. Responses of jobs finishing
+// after that are dropped, so the responses-channel can be closed either way.
+// Submit must not be called after Shutdown.
+func (m *JobManager) Shutdown(ctx context.Context) error {
+	err := waitContext(ctx, &m.running)
+	if err == nil {
+		return nil
+	}
+
+	var cancelled []*job
+	m.lock.Lock()
+	for _, j := range m.jobs {
+		if !isJobFinished(j.status.Status) {
+			j.cancel()
+			cancelled = append(cancelled, j)
+		}
+	}
+	m.lock.Unlock()
+
+	for _, j := range cancelled {
+		m.finish(j, JobCancelled, nil, errors.New("service shutting down"))
+	}
+
+	// Waits for responses being published, and stops further ones
+	m.publishLock.Lock()
+	m.stopped = true
+	m.publishLock.Unlock()
+	return err
+}
+
+// publish sends the job-response, unless the JobManager was stopped by
+// Shutdown, in which case the response is dropped.
+func (m *JobManager) publish(resp *model.KafkaResponse) {
+	m.publishLock.RLock()
+	defer m.publishLock.RUnlock()
+
+	if m.stopped {
+		log.Printf(
+			"Dropped response of cancelled job for event %s (%s)",
+			resp.UUID, resp.ServiceAction,
+		)
+		return
+	}
+	m.responses <- resp
+}
+
+func (m *JobManager) runJob(ctx context.Context, j *job, run JobFunc) {
+	defer m.running.Done()
+	defer j.cancel()
+
+	m.setProgress(j, JobRunning, "", 0)
+	result, errCode, err := m.runRecovered(ctx, j, run)
+
+	event := &j.event
+	if ctx.Err() == context.Canceled {
+		err = errors.New("job cancelled")
+		m.publish(errorResponse(event, err, JobCancelledError))
+		return
+	}
+	if err != nil {
+		m.finish(j, JobFailed, result, err)
+		m.publish(errorResponse(event, err, errCode))
+		return
+	}
+
+	resultMarshal, err := json.Marshal(result)
+	if err != nil {
+		err = errors.Wrap(err, "Error marshalling job-result")
+		m.finish(j, JobFailed, result, err)
+		m.publish(errorResponse(event, err, InternalError))
+		return
+	}
+	m.finish(j, JobCompleted, result, nil)
+	m.publish(resultResponse(event, resultMarshal))
+}
+
+// runRecovered runs the job, and fails it with InternalError if it panics.
+func (m *JobManager) runRecovered(
+	ctx context.Context,
+	j *job,
+	run JobFunc,
+) (result *report.QueryResult, errCode int16, err error) {
+	defer func() {
+		if recovered := recover(); recovered != nil {
+			result = nil
+			errCode = InternalError
+			err = eventPanic(recovered, &j.event)
+		}
+	}()
+
+	return run(ctx, func(stage string, progress int) {
+		m.setProgress(j, JobRunning, stage, progress)
+	})
+}
+
+// setProgress updates the job-status and publishes it as progress-event.
+// Updates are ignored once the job has finished or was cancelled.
+func (m *JobManager) setProgress(j *job, status string, stage string, progress int) {
+	m.lock.Lock()
+	if isJobFinished(j.status.Status) {
+		m.lock.Unlock()
+		return
+	}
+	j.status.Status = status
+	j.status.Stage = stage
+	j.status.Progress = progress
+	j.status.UpdatedAt = time.Now().Unix()
+	s := j.status
+	m.lock.Unlock()
+
+	m.publish(jobResponse(&j.event, s))
+}
+
+func (m *JobManager) finish(j *job, status string, result *report.QueryResult, err error) {
+	m.lock.Lock()
+	if isJobFinished(j.status.Status) {
+		m.lock.Unlock()
+		return
+	}
+	j.status.Status = status
+	j.status.UpdatedAt = time.Now().Unix()
+	if status == JobCompleted {
+		j.status.Progress = 100
+		j.status.Stage = ""
+	}
+	// Reports that weren't stored can't be looked up by their ReportID
+	if result != nil && result.Status != report.ReportStatusPartial {
+		j.status.ReportID = result.ReportID
+	}
+	if err != nil {
+		j.status.Error = err.Error()
+	}
+	s := j.status
+	m.lock.Unlock()
+
+	m.publish(jobResponse(&j.event, s))
+}
+
+// pruneFinished removes finished jobs older than jobRetention.
+// The lock must be held by the caller.
+func (m *JobManager) pruneFinished() {
+	cutoff := time.Now().Add(-jobRetention).Unix()
+	for id, j := range m.jobs {
+		if isJobFinished(j.status.Status) && j.status.UpdatedAt < cutoff {
+			delete(m.jobs, id)
+		}
+	}
+}
+
+func isJobFinished(status string) bool {
+	return status == JobCompleted || status == JobFailed || status == JobCancelled
+}
+
+// jobResponse creates a JobProgress KafkaResponse for the job's event.
+func jobResponse(event *model.Event, status JobStatus) *model.KafkaResponse {
+	statusMarshal, err := json.Marshal(status)
+	if err != nil {
+		err = errors.Wrap(err, "Error marshalling JobStatus")
+		log.Println(err)
+		return errorResponse(event, err, InternalError)
+	}
+	resp := resultResponse(event, statusMarshal)
+	resp.ServiceAction = JobProgressAction
+	return resp
+}
+
+// jobRequest is the Event-data for JobStatus and JobCancel events.
+// event.Data should be in this format: `{"jobID":"<uuid>"}`
+type jobRequest struct {
+	JobID string `json:"jobID"`
+}
+
+// JobQuery handles "JobStatus" and "JobCancel" events.
+func JobQuery(jobs *JobManager, event *model.Event) *model.KafkaResponse {
+	req := jobRequest{}
+	err := unmarshalEventData(event.Data, &req)
+	if err != nil {
+		err = errors.Wrap(err, "JobQuery: Error while unmarshalling Event-data")
+		return errorResponse(event, err, ValidationError)
+	}
+	jobID, err := uuuid.FromString(req.JobID)
+	if err != nil {
+		err = invalidField("jobID", "invalid jobID: %s", err.Error())
+		err = errors.Wrap(err, "JobQuery: Error parsing jobID")
+		return errorResponse(event, err, ValidationError)
+	}
+
+	var status JobStatus
+	var ok bool
+	if event.ServiceAction == JobCancelAction {
+		status, ok = jobs.Cancel(jobID)
+	} else {
+		status, ok = jobs.Status(jobID)
+	}
+	if !ok {
+		err = errors.Errorf("JobQuery: job \"%s\" not found", req.JobID)
+		return errorResponse(event, err, NotFoundError)
+	}
+
+	statusMarshal, err := json.Marshal(status)
+	if err != nil {
+		err = errors.Wrap(err, "JobQuery: Error marshalling JobStatus")
+		return errorResponse(event, err, InternalError)
+	}
+	return resultResponse(event, statusMarshal)
+}
@@ -0,0 +1,48 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"time"
+
+	"github.com/TerrexTech/agg-itemsoldflashsale-report/report"
+	"github.com/TerrexTech/go-eventstore-models/model"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("JobManager", func() {
+	It("stops publishing responses of jobs finishing after Shutdown", func() {
+		responses := make(chan *model.KafkaResponse, 10)
+		jobs := NewJobManager(responses)
+
+		finish := make(chan struct{})
+		_, err := jobs.Submit(&model.Event{}, 0, func(
+			ctx context.Context, _ ProgressFunc,
+		) (*report.QueryResult, int16, error) {
+			// Ignores cancellation, like a handler stuck in a Mongo-query
+			<-finish
+			return &report.QueryResult{}, 0, nil
+		})
+		Expect(err).ToNot(HaveOccurred())
+
+		ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+		defer cancel()
+		Expect(jobs.Shutdown(ctx)).To(Equal(context.DeadlineExceeded))
+
+		// The published statuses end with the cancelled one
+		var status JobStatus
+		for len(responses) > 0 {
+			resp := <-responses
+			Expect(json.Unmarshal(resp.Result, &status)).To(Succeed())
+		}
+		Expect(status.Status).To(Equal(JobCancelled))
+
+		// Sending on the closed channel would panic
+		close(responses)
+		close(finish)
+		waitCtx, waitCancel := context.WithTimeout(context.Background(), time.Second)
+		defer waitCancel()
+		Expect(waitContext(waitCtx, &jobs.running)).To(Succeed())
+	})
+})
@@ -1,15 +1,18 @@
 package main
 
 import (
+	"context"
 	"log"
 	"os"
+	"os/signal"
+	"syscall"
 
 	"github.com/TerrexTech/agg-itemsoldflashsale-report/report"
 	"github.com/TerrexTech/go-commonutils/commonutil"
-	"github.com/TerrexTech/go-eventspoll/poll"
+	"github.com/TerrexTech/go-eventstore-models/model"
 
-	"github.com/TerrexTech/go-kafkautils/kafka"
 	tlog "github.com/TerrexTech/go-logtransport/log"
+	"github.com/TerrexTech/go-mongoutils/mongo"
 	"github.com/joho/godotenv"
 	"github.com/pkg/errors"
 )
@@ -31,6 +34,7 @@ func validateEnv() error {
 		"MONGO_HOSTS",
 		"MONGO_DATABASE",
 		"MONGO_AGG_COLLECTION",
+		"MONGO_REPORT_COLLECTION",
 		"MONGO_META_COLLECTION",
 
 		"MONGO_CONNECTION_TIMEOUT_MS",
@@ -47,24 +51,6 @@ func validateEnv() error {
 	return nil
 }
 
-// func createData(numIterations int, repCollection *mongo.Collection) {
-// 	newReport := []report.FlashSaleSoldItem{}
-// 	for i := 0; i < numIterations; i++ {
-// 		newReport = append(newReport, report.InsertItemSold())
-// 	}
-
-// 	for range newReport {
-// 		for _, v := range newReport {
-// 			insertResult, err := repCollection.InsertOne(v)
-// 			if err != nil {
-// 				err = errors.Wrap(err, "Unable to insert data")
-// 				log.Println(err)
-// 			}
-// 			log.Println(insertResult)
-// 		}
-// 	}
-// }
-
 func main() {
 	log.Println("Reading environment file")
 	err := godotenv.Load("./.env")
@@ -80,194 +66,166 @@ func main() {
 		log.Fatalln(err)
 	}
 
-	aggCollection := os.Getenv("MONGO_AGG_COLLECTION")
-	reportCollection := os.Getenv("MONGO_REPORT_COLLECTION")
-	brokersStr := os.Getenv("KAFKA_BROKERS")
-	brokers := *commonutil.ParseHosts(brokersStr)
-	logTopic := os.Getenv("KAFKA_LOG_PRODUCER_TOPIC")
-	serviceName := os.Getenv("SERVICE_NAME")
-
 	log.Println("=================")
-	log.Println(serviceName)
+	log.Println(os.Getenv("SERVICE_NAME"))
 
-	prodConfig := &kafka.ProducerConfig{
-		KafkaBrokers: brokers,
-	}
-	logger, err := tlog.Init(nil, serviceName, prodConfig, logTopic)
+	report.SetResilience(loadResilience())
+
+	// Liveness is served during startup, while readiness fails until the
+	// dependencies are connected
+	health := NewHealthMonitor()
+	healthServer := startHealthServer(health)
+
+	// No requests are served until Kafka and Mongo are ready. The Logger
+	// produces to Kafka too, so startup errors are only logged locally.
+	deps, err := connectDependencies()
 	if err != nil {
-		err = errors.Wrap(err, "Error initializing Logger")
+		err = errors.Wrap(err, "Startup failed")
 		log.Fatalln(err)
 	}
+	logger := deps.logger
+	mc := deps.mongoConfig
+	eventPoll := deps.eventPoll
+	itemSoldColl := deps.itemSoldColl
 
-	kc, err := loadKafkaConfig()
-	if err != nil {
-		err = errors.Wrap(err, "Error in KafkaConfig")
-		logger.E(tlog.Entry{
-			Description: err.Error(),
-			ErrorCode:   1,
-		}, kc)
-	}
+	responses, responsesFlushed := chunkResponses(
+		eventPoll.ProduceResult(), loadMaxMessageBytes(),
+	)
+	jobs := NewJobManager(responses)
+	reqTimeout := loadRequestTimeout()
+	dispatcher := newServiceDispatcher(
+		logger, itemSoldColl, mc.AggCollection, jobs, reqTimeout,
+	)
 
-	//This is for report collection
-	mc, err := loadMongoConfig(reportCollection, &report.SoldReport{})
+	deadLetters, err := loadDeadLetterQueue()
 	if err != nil {
-		err = errors.Wrap(err, "Error in MongoConfig - trying to load FlashsaleReport - mongoCollection")
+		err = errors.Wrap(err, "Startup failed: Error creating DeadLetterQueue")
 		logger.E(tlog.Entry{
 			Description: err.Error(),
 			ErrorCode:   1,
 		})
+		log.Fatalln(err)
 	}
 
-	ioConfig := poll.IOConfig{
-		ReadConfig: poll.ReadConfig{
-			EnableQuery: true,
-		},
-		KafkaConfig: *kc,
-		MongoConfig: *mc,
-	}
-
-	eventPoll, err := poll.Init(ioConfig)
-	if err != nil {
-		err = errors.Wrap(err, "Error creating EventPoll service")
-		logger.F(tlog.Entry{
-			Description: err.Error(),
-			ErrorCode:   1,
-		}, eventPoll)
+	// Aggregations of events, async jobs and the HTTP, gRPC and GraphQL APIs
+	// share the same limit as the worker-pool
+	poolSize, queueSize := loadWorkerPoolConfig()
+	report.SetAggregationLimit(poolSize)
+	workers := NewWorkerPool(poolSize, queueSize, func(event *model.Event) {
+		kafkaResp := deadLetters.Dispatch(context.Background(), dispatcher, event)
+		if kafkaResp != nil {
+			responses <- kafkaResp
+		}
+		health.EventHandled()
+	})
+	health.SetDependencies(eventPoll, mc.AggCollection, workers, jobs)
+
+	svc := &service{
+		eventPoll:        eventPoll,
+		workers:          workers,
+		jobs:             jobs,
+		responses:        responses,
+		responsesFlushed: responsesFlushed,
+		deadLetters:      deadLetters,
+		health:           health,
+		healthServer:     healthServer,
+		mongoClients:     []*mongo.Client{deps.mongoClient, mc.Connection.Client},
 	}
 
-	client, err := CreateClient()
-	if err != nil {
-		err = errors.Wrap(err, "Error in MongoClient")
-		logger.E(tlog.Entry{
-			Description: err.Error(),
-			ErrorCode:   1,
-		}, client)
+	// HTTP API is optional, and only served if an address is set
+	httpAddr := os.Getenv("HTTP_LISTEN_ADDR")
+	if httpAddr != "" {
+		handler := newHTTPHandler(dispatcher)
+		gqlHandler, err := newGraphQLHandler(logger, itemSoldColl, mc.AggCollection)
+		if err != nil {
+			err = errors.Wrap(err, "Error creating GraphQL handler")
+			logger.E(tlog.Entry{
+				Description: err.Error(),
+				ErrorCode:   1,
+			})
+		} else {
+			handler.mux.Handle("/graphql", gqlHandler)
+		}
+		svc.httpServer = startHTTPServer(httpAddr, handler)
 	}
 
-	itemSoldColl, err := CreateCollection(client, aggCollection, &report.FlashSaleSoldItem{})
-	if err != nil {
-		err = errors.Wrap(err, "Error in MongoCollection")
-		logger.E(tlog.Entry{
-			Description: err.Error(),
-			ErrorCode:   1,
+	// gRPC API is optional, and only served if an address is set
+	grpcAddr := os.Getenv("GRPC_LISTEN_ADDR")
+	if grpcAddr != "" {
+		svc.grpcServer, err = startGRPCServer(grpcAddr, &grpcServer{
+			logger:       logger,
+			itemSoldColl: itemSoldColl,
+			reportColl:   mc.AggCollection,
+			timeout:      reqTimeout,
 		})
+		if err != nil {
+			err = errors.Wrap(err, "Error starting gRPC server")
+			logger.E(tlog.Entry{
+				Description: err.Error(),
+				ErrorCode:   1,
+			})
+		}
 	}
 
+	// Consumption stops on SIGTERM/SIGINT, or if the service-context closes
+	consumeCtx, stopConsuming := context.WithCancel(eventPoll.RoutinesCtx())
+	signals := make(chan os.Signal, 1)
+	signal.Notify(signals, syscall.SIGTERM, os.Interrupt)
+	go func() {
+		select {
+		case sig := <-signals:
+			log.Printf("Received %s, shutting down", sig)
+			stopConsuming()
+		case <-consumeCtx.Done():
+		}
+	}()
+
+	exitCode := 0
+consume:
 	for {
 		select {
-		case <-eventPoll.RoutinesCtx().Done():
-			err = errors.New("service-context closed")
-			log.Fatalln(err)
+		case <-consumeCtx.Done():
+			if eventPoll.RoutinesCtx().Err() != nil {
+				err = errors.New("service-context closed, shutting down")
+				log.Println(err)
+				exitCode = 1
+			}
+			break consume
 
 		case eventResp := <-eventPoll.Query():
-			go func(eventResp *poll.EventResponse) {
-				if eventResp == nil {
-					return
-				}
-				err := eventResp.Error
-				if err != nil {
-					err = errors.Wrap(err, "Error in Query-EventResponse")
-					logger.D(tlog.Entry{
-						Description: err.Error(),
-						ErrorCode:   1,
-					})
-					return
-				}
-				kafkaResp := Query(itemSoldColl, mc.AggCollection, &eventResp.Event)
-				if kafkaResp != nil {
-					eventPoll.ProduceResult() <- kafkaResp
-				}
-			}(eventResp)
+			if eventResp == nil {
+				continue
+			}
+			err := eventResp.Error
+			if err != nil {
+				err = errors.Wrap(err, "Error in Query-EventResponse")
+				logger.D(tlog.Entry{
+					Description: err.Error(),
+					ErrorCode:   1,
+				})
+				continue
+			}
+			// Blocks while the worker-queue is full, which pauses consumption
+			event := eventResp.Event
+			err = workers.Submit(consumeCtx, &event)
+			if err != nil {
+				err = errors.Wrap(err, "Error queueing event")
+				logger.E(tlog.Entry{
+					Description: err.Error(),
+					ErrorCode:   1,
+				}, event)
+			}
 		}
 	}
+	stopConsuming()
 
-	// client, err := CreateClient()
-	// if err != nil {
-	// 	err = errors.Wrap(err, "Error in MongoClient")
-	// 	log.Fatalln(err)
-	// }
-
-	// itemSoldColl, err := CreateCollection(client, "agg_flashitemsold", &report.FlashSaleSoldItem{})
-	// if err != nil {
-	// 	err = errors.Wrap(err, "Error in MongoCollection")
-	// 	log.Fatalln(err)
-	// }
-
-	// // createData(10, itemSoldColl)
-
-	// searchParameters := []byte(`{"timestamp":{"$gt":1539315000},"timestamp":{"$lt":1541997372}}`)
-
-	// soldItemsParam := report.SoldItemParams{}
-	// err = json.Unmarshal(searchParameters, &soldItemsParam)
-	// if err != nil {
-	// 	err = errors.Wrap(err, "Error in Unmarshalling searchParameters")
-	// 	log.Fatalln(err)
-	// }
-
-	// avgSoldReport, err := report.ItemSoldReport(soldItemsParam, itemSoldColl)
-	// if err != nil {
-	// 	err = errors.Wrap(err, "Error getting results ")
-	// 	log.Fatalln(err)
-	// }
-
-	// if len(avgSoldReport) > 0 {
-	// 	var reportAgg []report.ReportResult
-
-	// 	for _, v := range avgSoldReport {
-	// 		m, assertOK := v.(map[string]interface{})
-	// 		if !assertOK {
-	// 			err = errors.New("Error getting results ")
-	// 			log.Fatalln(err)
-	// 		}
-
-	// 		groupByFields := m["_id"]
-	// 		mapInGroupBy := groupByFields.(map[string]interface{})
-	// 		sku := mapInGroupBy["sku"].(string)
-	// 		name := mapInGroupBy["name"].(string)
-
-	// 		reportAgg = []report.ReportResult{
-	// 			report.ReportResult{
-	// 				SKU:         sku,
-	// 				Name:        name,
-	// 				SoldWeight:  m["avg_sold"].(float64),
-	// 				TotalWeight: m["avg_total"].(float64),
-	// 			},
-	// 		}
-	// 	}
-
-	// 	client, err = CreateClient()
-	// 	if err != nil {
-	// 		err = errors.Wrap(err, "Error in MongoClient")
-	// 		log.Fatalln(err)
-	// 	}
-
-	// 	reportColl, err := CreateCollection(client, "agg_report_flashitemsold", &report.SoldReport{})
-	// 	if err != nil {
-	// 		err = errors.Wrap(err, "Error in MongoCollection")
-	// 		log.Fatalln(err)
-	// 	}
-
-	// 	reportID, err := uuuid.NewV4()
-	// 	if err != nil {
-	// 		err = errors.Wrap(err, "Error in generating reportID ")
-	// 		log.Fatalln(err)
-	// 	}
-
-	// 	reportGen := report.SoldReport{
-	// 		ReportID:     reportID,
-	// 		SearchQuery:  soldItemsParam,
-	// 		ReportResult: reportAgg,
-	// 	}
-
-	// 	repInsert, err := report.CreateReport(reportGen, reportColl)
-	// 	if err != nil {
-	// 		err = errors.Wrap(err, "Error in inserted report")
-	// 		log.Fatalln(err)
-	// 	}
-
-	// 	log.Println(repInsert)
-	// } else {
-	// 	err = errors.New("No results founds in reports")
-	// 	log.Fatalln(err)
-	// }
+	err = svc.shutdown(loadShutdownTimeout())
+	if err != nil {
+		err = errors.Wrap(err, "Shutdown did not complete")
+		log.Println(err)
+		exitCode = 1
+	} else {
+		log.Println("Shutdown complete")
+	}
+	os.Exit(exitCode)
 }
@@ -0,0 +1,106 @@
+package main
+
+import (
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// metricsNamespace prefixes all metrics exported by this service.
+const metricsNamespace = "itemsoldflash_report"
+
+// unknownActionLabel is the "action"-label for ServiceActions this service
+// doesn't handle.
+const unknownActionLabel = "unknown"
+
+// actionLabel returns the "action"-label for the ServiceAction. Actions are
+// set by clients, so unknown actions share a single label, which keeps the
+// number of series bounded.
+func actionLabel(serviceAction string) string {
+	if _, ok := serviceActions[serviceAction]; ok {
+		return serviceAction
+	}
+	return unknownActionLabel
+}
+
+var (
+	// eventQueueWait is how long events waited in the worker-queue before
+	// a worker picked them up.
+	eventQueueWait = prometheus.NewHistogramVec(
+		prometheus.HistogramOpts{
+			Namespace: metricsNamespace,
+			Name:      "event_queue_wait_seconds",
+			Help:      "Time query-events waited in the worker-queue.",
+			Buckets:   []float64{.001, .005, .01, .05, .1, .5, 1, 5, 10, 30},
+		},
+		[]string{"action"},
+	)
+	// eventQueueDepth is the number of events waiting in the worker-queue.
+	eventQueueDepth = prometheus.NewGauge(prometheus.GaugeOpts{
+		Namespace: metricsNamespace,
+		Name:      "event_queue_depth",
+		Help:      "Number of query-events waiting in the worker-queue.",
+	})
+	// eventsInFlight is the number of events being handled by workers.
+	eventsInFlight = prometheus.NewGauge(prometheus.GaugeOpts{
+		Namespace: metricsNamespace,
+		Name:      "events_in_flight",
+		Help:      "Number of query-events being handled by workers.",
+	})
+	// eventQueueFull counts how often consumption paused on a full queue.
+	eventQueueFull = prometheus.NewCounter(prometheus.CounterOpts{
+		Namespace: metricsNamespace,
+		Name:      "event_queue_full_total",
+		Help:      "Number of times event-consumption paused because the worker-queue was full.",
+	})
+	// handlerPanics counts panics recovered from handlers, per ServiceAction
+	// or gRPC-method.
+	handlerPanics = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Namespace: metricsNamespace,
+			Name:      "handler_panics_total",
+			Help:      "Number of panics recovered from request-handlers.",
+		},
+		[]string{"action"},
+	)
+	// eventsDeadLettered counts events published to the dead-letter topic.
+	eventsDeadLettered = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Namespace: metricsNamespace,
+			Name:      "events_dead_lettered_total",
+			Help:      "Number of query-events published to the dead-letter topic.",
+		},
+		[]string{"action"},
+	)
+	// mongoRetries counts retried Mongo-operations.
+	mongoRetries = prometheus.NewCounter(prometheus.CounterOpts{
+		Namespace: metricsNamespace,
+		Name:      "mongo_retries_total",
+		Help:      "Number of Mongo-operations retried after a transient failure.",
+	})
+	// mongoCircuitOpen is 1 while the Mongo circuit-breaker is open or
+	// half-open, and 0 while it is closed.
+	mongoCircuitOpen = prometheus.NewGauge(prometheus.GaugeOpts{
+		Namespace: metricsNamespace,
+		Name:      "mongo_circuit_open",
+		Help:      "Whether the Mongo circuit-breaker fails operations fast.",
+	})
+	// reportsNotStored counts reports that were computed but not stored.
+	reportsNotStored = prometheus.NewCounter(prometheus.CounterOpts{
+		Namespace: metricsNamespace,
+		Name:      "reports_not_stored_total",
+		Help:      "Number of reports returned as partial because storing them failed.",
+	})
+)
+
+func init() {
+	prometheus.MustRegister(
+		eventQueueWait,
+		eventQueueDepth,
+		eventsInFlight,
+		eventQueueFull,
+		handlerPanics,
+		eventsDeadLettered,
+		mongoRetries,
+		mongoCircuitOpen,
+		reportsNotStored,
+	)
+}
@@ -1,183 +1,223 @@
 package main
 
 import (
+	"context"
 	"encoding/json"
-	"log"
-	"os"
+	"time"
 
 	"github.com/TerrexTech/agg-itemsoldflashsale-report/report"
-	"github.com/TerrexTech/go-commonutils/commonutil"
 	"github.com/TerrexTech/go-eventstore-models/model"
-	"github.com/TerrexTech/go-kafkautils/kafka"
 	tlog "github.com/TerrexTech/go-logtransport/log"
 	"github.com/TerrexTech/go-mongoutils/mongo"
 	"github.com/TerrexTech/uuuid"
 	"github.com/pkg/errors"
 )
 
-// Query handles "query" events.
-func Query(itemSoldColl *mongo.Collection, reportColl *mongo.Collection, event *model.Event) *model.KafkaResponse {
-
-	brokersStr := os.Getenv("KAFKA_BROKERS")
-	brokers := *commonutil.ParseHosts(brokersStr)
-	logTopic := os.Getenv("KAFKA_LOG_PRODUCER_TOPIC")
-	serviceName := os.Getenv("SERVICE_NAME")
-
-	prodConfig := &kafka.ProducerConfig{
-		KafkaBrokers: brokers,
-	}
-	logger, err := tlog.Init(nil, serviceName, prodConfig, logTopic)
-	if err != nil {
-		err = errors.Wrap(err, "Error initializing Logger")
-		log.Fatalln(err)
-	}
+// soldItemRequest is the Event-data for SoldItemSummary events.
+type soldItemRequest struct {
+	report.SoldItemParams
+	// Async runs the report as background-job. The event is acknowledged
+	// with the job-status, and the report is delivered on completion.
+	Async bool `json:"async,omitempty"`
+	// Format of the result. Async reports are always delivered as JSON.
+	exportOptions
+	// TimeoutMS is the request-deadline. For async reports, it limits the
+	// background-job, which otherwise runs until completion.
+	requestTimeout
+}
 
-	//This is where it starts
+// Query handles "SoldItemSummary" query-events.
+func Query(
+	ctx context.Context,
+	logger tlog.Logger,
+	itemSoldColl *mongo.Collection,
+	reportColl *mongo.Collection,
+	jobs *JobManager,
+	event *model.Event,
+) *model.KafkaResponse {
 	// event.Data should be in this format: `{"timestamp":{"$gt":1529315000},"timestamp":{"$lt":1551997372}}`
+	// Add `"async":true` to run the report as background-job, and
+	// `"format":"csv"` to get the report-rows as CSV, and `"timeoutMS":30000`
+	// to set the request-deadline.
 
-	filter := report.SoldItemParams{}
-
-	var reportAgg []report.ReportResult
+	req := soldItemRequest{}
 
-	err = json.Unmarshal(event.Data, &filter)
+	err := unmarshalEventData(event.Data, &req)
 	if err != nil {
 		err = errors.Wrap(err, "Query: Error while unmarshalling Event-data - ItemSoldFlashSaleReport")
 		logger.E(tlog.Entry{
 			Description: err.Error(),
 			ErrorCode:   1,
-		}, filter)
-		return &model.KafkaResponse{
-			AggregateID:   event.AggregateID,
-			CorrelationID: event.CorrelationID,
-			Error:         err.Error(),
-			ErrorCode:     InternalError,
-			EventAction:   event.EventAction,
-			ServiceAction: event.ServiceAction,
-			UUID:          event.UUID,
-		}
+		}, string(event.Data))
+		return errorResponse(event, err, ValidationError)
 	}
+	filter := req.SoldItemParams
+	isJSON := req.Format == "" || req.Format == ExportFormatJSON
 
-	if &filter == nil {
-		err = errors.New("blank filter provided")
-		err = errors.Wrap(err, "Query left blank - ItemSoldFlashSaleReport")
+	err = filter.Validate()
+	if err != nil {
+		err = errors.Wrap(err, "Query: Invalid search-params")
 		logger.E(tlog.Entry{
 			Description: err.Error(),
 			ErrorCode:   1,
-		}, filter)
-		return &model.KafkaResponse{
-			AggregateID:   event.AggregateID,
-			CorrelationID: event.CorrelationID,
-			Error:         err.Error(),
-			ErrorCode:     InternalError,
-			EventAction:   event.EventAction,
-			ServiceAction: event.ServiceAction,
-			UUID:          event.UUID,
-		}
+		}, req)
+		return errorResponse(event, err, ValidationError)
 	}
-
-	avgSoldReport, err := report.ItemSoldReport(filter, itemSoldColl)
+	err = req.exportOptions.validate()
 	if err != nil {
-		err = errors.Wrap(err, "Error getting results from ItemSoldFlashSaleCollection")
+		err = errors.Wrap(err, "Query: Invalid export-options")
 		logger.E(tlog.Entry{
 			Description: err.Error(),
 			ErrorCode:   1,
-		}, filter)
+		}, req)
+		return errorResponse(event, err, ValidationError)
 	}
-
-	if len(avgSoldReport) < 1 {
-		err = errors.New("Error: No result found from agg_itemsoldFlashSale collection - Function = ItemSoldFlashSaleReport")
+	if req.Async && !isJSON {
+		err = invalidField(
+			"format",
+			"format \"%s\" is not supported for async reports, use ReportExport instead",
+			req.Format,
+		)
+		err = errors.Wrap(err, "Query: Invalid export-options")
 		logger.E(tlog.Entry{
 			Description: err.Error(),
 			ErrorCode:   1,
-		}, reportAgg)
-		return &model.KafkaResponse{
-			AggregateID:   event.AggregateID,
-			CorrelationID: event.CorrelationID,
-			Error:         err.Error(),
-			ErrorCode:     InternalError,
-			EventAction:   event.EventAction,
-			ServiceAction: event.ServiceAction,
-			UUID:          event.UUID,
-		}
+		}, req)
+		return errorResponse(event, err, ValidationError)
 	}
 
-	for _, v := range avgSoldReport {
-		m, assertOK := v.(map[string]interface{})
-		if !assertOK {
-			err = errors.New("Error getting results from asserting AvgSoldReport into map[string]interface{}")
+	if req.Async {
+		status, err := jobs.Submit(
+			event,
+			req.requestTimeout.duration(),
+			func(ctx context.Context, progress ProgressFunc) (*report.QueryResult, int16, error) {
+				return generateReport(
+					ctx, logger, itemSoldColl, reportColl, filter, report.DefaultAggregation(), progress,
+				)
+			},
+		)
+		if err != nil {
+			err = errors.Wrap(err, "Query: Error submitting report-job")
 			logger.E(tlog.Entry{
 				Description: err.Error(),
 				ErrorCode:   1,
-			}, m)
+			}, filter)
+			return errorResponse(event, err, InternalError)
 		}
+		return jobResponse(event, status)
+	}
 
-		groupByFields := m["_id"]
-		mapInGroupBy := groupByFields.(map[string]interface{})
-		sku := mapInGroupBy["sku"].(string)
-		name := mapInGroupBy["name"].(string)
-
-		// log.Println(m, "#############")
+	result, errCode, err := generateReport(
+		ctx, logger, itemSoldColl, reportColl, filter, report.DefaultAggregation(), nil,
+	)
+	if err != nil {
+		return errorResponse(event, err, errCode)
+	}
 
-		//if it crashes on soldFlashSaleWeight - check the soldFlashSaleWeight field inside db and inside item_soldFlashSale file--- inside the aggregate pipeline they should match
-		reportAgg = append(reportAgg, report.ReportResult{
-			SKU:         sku,
-			Name:        name,
-			SoldWeight:  m["avg_sold"].(float64),
-			TotalWeight: m["avg_total"].(float64),
-		})
+	if !isJSON {
+		export, err := exportReport(&report.SoldReport{
+			ReportID:     result.ReportID,
+			SearchQuery:  filter,
+			ReportResult: result.ReportResult,
+			Timestamp:    time.Now().Unix(),
+			Empty:        result.Empty,
+		}, req.exportOptions)
+		if err != nil {
+			err = errors.Wrap(err, "Query: Error exporting report")
+			logger.E(tlog.Entry{
+				Description: err.Error(),
+				ErrorCode:   1,
+			}, req)
+			return errorResponse(event, err, InternalError)
+		}
+		return resultResponse(event, export)
 	}
 
-	reportID, err := uuuid.NewV4()
+	resultMarshal, err := json.Marshal(result)
 	if err != nil {
-		err = errors.Wrap(err, "Error in generating reportID ")
+		err = errors.Wrap(err, "Query: Error marshalling report ItemSoldFlashSaleResults - called reportAgg")
 		logger.E(tlog.Entry{
 			Description: err.Error(),
 			ErrorCode:   1,
-		})
+		}, result)
+		return errorResponse(event, err, InternalError)
 	}
 
-	reportGen := report.SoldReport{
-		ReportID:     reportID,
-		SearchQuery:  filter,
-		ReportResult: reportAgg,
+	return resultResponse(event, resultMarshal)
+}
+
+// generateReport runs the aggregation for the filter, and stores the
+// generated report. The progress-func is optional. The report is
+// abandoned if ctx is done, with TimeoutError if its deadline expired.
+func generateReport(
+	ctx context.Context,
+	logger tlog.Logger,
+	itemSoldColl *mongo.Collection,
+	reportColl *mongo.Collection,
+	filter report.SoldItemParams,
+	spec report.AggregationSpec,
+	progress ProgressFunc,
+) (*report.QueryResult, int16, error) {
+	if progress == nil {
+		progress = func(string, int) {}
 	}
 
-	repInsert, err := report.CreateReport(reportGen, reportColl)
+	progress("aggregating", 10)
+	avgSoldReport, err := report.Aggregate(ctx, filter, spec, itemSoldColl)
+	if ctx.Err() != nil {
+		return nil, contextErrorCode(ctx.Err(), InternalError), ctx.Err()
+	}
 	if err != nil {
-		err = errors.Wrap(err, "Error in inserting report to mongo")
+		err = errors.Wrap(err, "Error getting results from ItemSoldFlashSaleCollection")
 		logger.E(tlog.Entry{
 			Description: err.Error(),
 			ErrorCode:   1,
-		}, reportGen)
+		}, filter)
+		return nil, validationErrorCode(err, DatabaseError), err
 	}
 
-	log.Println(repInsert)
-	log.Println(reportAgg, "$$$$$$$$$$$$$$$")
-
-	resultMarshal, err := json.Marshal(reportAgg)
+	progress("decoding", 60)
+	reportID, err := uuuid.NewV4()
 	if err != nil {
-		err = errors.Wrap(err, "Query: Error marshalling report ItemSoldFlashSaleResults - called reportAgg")
+		err = errors.Wrap(err, "Error in generating reportID ")
 		logger.E(tlog.Entry{
 			Description: err.Error(),
 			ErrorCode:   1,
-		}, reportAgg)
-		return &model.KafkaResponse{
-			AggregateID:   event.AggregateID,
-			CorrelationID: event.CorrelationID,
-			Error:         err.Error(),
-			ErrorCode:     InternalError,
-			EventAction:   event.EventAction,
-			ServiceAction: event.ServiceAction,
-			UUID:          event.UUID,
-		}
+		})
 	}
+	reportGen, rowErrors := report.NewSoldReport(reportID, filter, spec, avgSoldReport)
+	for _, rowErr := range rowErrors {
+		err = errors.Errorf(
+			"Error decoding aggregation-row %d: %s", rowErr.Index, rowErr.Error,
+		)
+		logger.E(tlog.Entry{
+			Description: err.Error(),
+			ErrorCode:   1,
+		}, avgSoldReport[rowErr.Index])
+	}
+	if ctx.Err() != nil {
+		return nil, contextErrorCode(ctx.Err(), InternalError), ctx.Err()
+	}
+	result := report.NewQueryResult(reportGen, rowErrors)
 
-	return &model.KafkaResponse{
-		AggregateID:   event.AggregateID,
-		CorrelationID: event.CorrelationID,
-		EventAction:   event.EventAction,
-		Result:        resultMarshal,
-		ServiceAction: event.ServiceAction,
-		UUID:          event.UUID,
+	progress("storing", 80)
+	_, err = report.CreateReport(ctx, reportGen, reportColl)
+	if ctx.Err() != nil {
+		return nil, contextErrorCode(ctx.Err(), InternalError), ctx.Err()
+	}
+	// The computed report is still returned, flagged as partial, since the
+	// caller can use it even though it can't be looked up later.
+	if err != nil {
+		err = errors.Wrap(err, "Error in inserting report to mongo")
+		logger.E(tlog.Entry{
+			Description: err.Error(),
+			ErrorCode:   1,
+		}, reportGen)
+		reportsNotStored.Inc()
+		result.Status = report.ReportStatusPartial
+		result.StoreError = err.Error()
+		return result, 0, nil
 	}
+
+	return result, 0, nil
 }
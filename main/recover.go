@@ -0,0 +1,80 @@
+package main
+
+import (
+	"context"
+	"log"
+	"runtime/debug"
+
+	"github.com/TerrexTech/go-eventstore-models/model"
+	"github.com/TerrexTech/uuuid"
+	"github.com/pkg/errors"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// eventMetadata identifies the event in panic-logs. The event-data is
+// left out, since it can be large.
+type eventMetadata struct {
+	UUID          uuuid.UUID
+	CorrelationID uuuid.UUID
+	AggregateID   int8
+	ServiceAction string
+	DataBytes     int
+}
+
+// handlerPanic converts the value recovered from a panicking handler into
+// an error. The error is logged with the stack-trace and the metadata, and
+// counted per action.
+func handlerPanic(recovered interface{}, action string, metadata interface{}) error {
+	handlerPanics.WithLabelValues(action).Inc()
+
+	err := errors.Errorf("panic while handling request: %v", recovered)
+	log.Printf("%s\nmetadata: %+v\n%s", err, metadata, debug.Stack())
+	return err
+}
+
+// eventPanic is handlerPanic for query-events.
+func eventPanic(recovered interface{}, event *model.Event) error {
+	return handlerPanic(recovered, actionLabel(event.ServiceAction), eventMetadata{
+		UUID:          event.UUID,
+		CorrelationID: event.CorrelationID,
+		AggregateID:   event.AggregateID,
+		ServiceAction: event.ServiceAction,
+		DataBytes:     len(event.Data),
+	})
+}
+
+// grpcRecoverUnary returns an Internal status-error for panicking unary
+// gRPC-handlers.
+func grpcRecoverUnary(
+	ctx context.Context,
+	req interface{},
+	info *grpc.UnaryServerInfo,
+	handler grpc.UnaryHandler,
+) (resp interface{}, err error) {
+	defer func() {
+		if recovered := recover(); recovered != nil {
+			panicErr := handlerPanic(recovered, info.FullMethod, info.FullMethod)
+			err = status.Error(codes.Internal, panicErr.Error())
+		}
+	}()
+	return handler(ctx, req)
+}
+
+// grpcRecoverStream returns an Internal status-error for panicking
+// streaming gRPC-handlers.
+func grpcRecoverStream(
+	srv interface{},
+	stream grpc.ServerStream,
+	info *grpc.StreamServerInfo,
+	handler grpc.StreamHandler,
+) (err error) {
+	defer func() {
+		if recovered := recover(); recovered != nil {
+			panicErr := handlerPanic(recovered, info.FullMethod, info.FullMethod)
+			err = status.Error(codes.Internal, panicErr.Error())
+		}
+	}()
+	return handler(srv, stream)
+}
@@ -0,0 +1,86 @@
+package main
+
+import (
+	"context"
+
+	"github.com/TerrexTech/agg-itemsoldflashsale-report/report"
+	"github.com/TerrexTech/go-eventstore-models/model"
+	tlog "github.com/TerrexTech/go-logtransport/log"
+	"github.com/TerrexTech/go-mongoutils/mongo"
+	"github.com/pkg/errors"
+)
+
+// chartRequest is the Event-data for ReportChart.
+// event.Data should be in this format:
+// `{"reportID":"<uuid>","chart":{"type":"line","format":"png"},"store":true}`
+type chartRequest struct {
+	ReportID string              `json:"reportID"`
+	Chart    report.ChartOptions `json:"chart"`
+	// Store saves the chart alongside the report, replacing any stored chart
+	// of the same type and format.
+	Store bool `json:"store,omitempty"`
+}
+
+// ReportChart handles "ReportChart" events, and returns a chart-image of the
+// stored report.
+func ReportChart(
+	ctx context.Context,
+	logger tlog.Logger,
+	reportColl *mongo.Collection,
+	event *model.Event,
+) *model.KafkaResponse {
+	req := chartRequest{}
+	err := unmarshalEventData(event.Data, &req)
+	if err != nil {
+		err = errors.Wrap(err, "ReportChart: Error while unmarshalling Event-data")
+		logger.E(tlog.Entry{
+			Description: err.Error(),
+			ErrorCode:   1,
+		}, string(event.Data))
+		return errorResponse(event, err, ValidationError)
+	}
+	err = req.Chart.Validate()
+	if err != nil {
+		err = errors.Wrap(report.PrefixFields("chart", err), "ReportChart: Invalid chart-options")
+		logger.E(tlog.Entry{
+			Description: err.Error(),
+			ErrorCode:   1,
+		}, req)
+		return errorResponse(event, err, ValidationError)
+	}
+
+	rep, errCode, err := findRequestedReport(ctx, reportColl, reportRequest{
+		ReportID: req.ReportID,
+	})
+	if err != nil {
+		err = errors.Wrap(err, "ReportChart: Error finding report")
+		logger.E(tlog.Entry{
+			Description: err.Error(),
+			ErrorCode:   1,
+		}, req)
+		return errorResponse(event, err, errCode)
+	}
+
+	chart, err := report.RenderChart(rep, req.Chart)
+	if err != nil {
+		err = errors.Wrap(report.PrefixFields("chart", err), "ReportChart: Error rendering chart")
+		logger.E(tlog.Entry{
+			Description: err.Error(),
+			ErrorCode:   1,
+		}, req)
+		return errorResponse(event, err, validationErrorCode(err, InternalError))
+	}
+
+	if req.Store {
+		err = report.StoreChart(ctx, rep.ReportID, *chart, reportColl)
+		if err != nil {
+			err = errors.Wrap(err, "ReportChart: Error storing chart")
+			logger.E(tlog.Entry{
+				Description: err.Error(),
+				ErrorCode:   1,
+			}, req)
+			return errorResponse(event, err, contextErrorCode(err, DatabaseError))
+		}
+	}
+	return resultResponse(event, chart.Data)
+}
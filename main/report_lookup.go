@@ -0,0 +1,259 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+
+	"github.com/TerrexTech/agg-itemsoldflashsale-report/report"
+	"github.com/TerrexTech/go-eventstore-models/model"
+	tlog "github.com/TerrexTech/go-logtransport/log"
+	"github.com/TerrexTech/go-mongoutils/mongo"
+	"github.com/TerrexTech/uuuid"
+	"github.com/pkg/errors"
+)
+
+// reportRequest is the Event-data for actions operating on a stored report.
+// event.Data should be in this format: `{"reportID":"<uuid>","format":"json"}`
+type reportRequest struct {
+	ReportID string `json:"reportID"`
+	exportOptions
+}
+
+// Export formats.
+const (
+	// ExportFormatJSON exports the report as JSON. This is the default format.
+	ExportFormatJSON = "json"
+	// ExportFormatCSV exports the report-rows as CSV.
+	ExportFormatCSV = "csv"
+	// ExportFormatXLSX exports the report as XLSX workbook.
+	ExportFormatXLSX = "xlsx"
+	// ExportFormatHTML exports the report as self-contained HTML page.
+	ExportFormatHTML = "html"
+)
+
+// exportOptions select the format in which reports are exported.
+type exportOptions struct {
+	Format string             `json:"format,omitempty"`
+	CSV    *report.CSVOptions `json:"csv,omitempty"`
+}
+
+// validate checks for unsupported formats and invalid format-options.
+func (o exportOptions) validate() error {
+	switch o.Format {
+	case "", ExportFormatJSON, ExportFormatCSV, ExportFormatXLSX, ExportFormatHTML:
+	default:
+		return invalidField("format", "unsupported export format: \"%s\"", o.Format)
+	}
+	if o.CSV != nil {
+		return report.PrefixFields("csv", o.CSV.Validate())
+	}
+	return nil
+}
+
+// ReportLookup handles "ReportLookup" events, and returns the stored report.
+func ReportLookup(
+	ctx context.Context,
+	logger tlog.Logger,
+	reportColl *mongo.Collection,
+	event *model.Event,
+) *model.KafkaResponse {
+	req := reportRequest{}
+	err := unmarshalEventData(event.Data, &req)
+	if err != nil {
+		err = errors.Wrap(err, "ReportLookup: Error while unmarshalling Event-data")
+		logger.E(tlog.Entry{
+			Description: err.Error(),
+			ErrorCode:   1,
+		}, string(event.Data))
+		return errorResponse(event, err, ValidationError)
+	}
+
+	rep, errCode, err := findRequestedReport(ctx, reportColl, req)
+	if err != nil {
+		err = errors.Wrap(err, "ReportLookup: Error finding report")
+		logger.E(tlog.Entry{
+			Description: err.Error(),
+			ErrorCode:   1,
+		}, req)
+		return errorResponse(event, err, errCode)
+	}
+
+	resultMarshal, err := json.Marshal(rep)
+	if err != nil {
+		err = errors.Wrap(err, "ReportLookup: Error marshalling report")
+		logger.E(tlog.Entry{
+			Description: err.Error(),
+			ErrorCode:   1,
+		}, rep)
+		return errorResponse(event, err, InternalError)
+	}
+	return resultResponse(event, resultMarshal)
+}
+
+// ReportHistory handles "ReportHistory" events, and returns the stored reports,
+// newest first.
+// event.Data should be in this format: `{"limit":20,"skip":0}`
+func ReportHistory(
+	ctx context.Context,
+	logger tlog.Logger,
+	reportColl *mongo.Collection,
+	event *model.Event,
+) *model.KafkaResponse {
+	params := report.HistoryParams{}
+	if len(event.Data) > 0 {
+		err := unmarshalEventData(event.Data, &params)
+		if err != nil {
+			err = errors.Wrap(err, "ReportHistory: Error while unmarshalling Event-data")
+			logger.E(tlog.Entry{
+				Description: err.Error(),
+				ErrorCode:   1,
+			}, string(event.Data))
+			return errorResponse(event, err, ValidationError)
+		}
+	}
+
+	reports, err := report.ListReports(ctx, params, reportColl)
+	if err != nil {
+		err = errors.Wrap(err, "ReportHistory: Error listing reports")
+		logger.E(tlog.Entry{
+			Description: err.Error(),
+			ErrorCode:   1,
+		}, params)
+		return errorResponse(event, err, contextErrorCode(err, DatabaseError))
+	}
+
+	resultMarshal, err := json.Marshal(reports)
+	if err != nil {
+		err = errors.Wrap(err, "ReportHistory: Error marshalling reports")
+		logger.E(tlog.Entry{
+			Description: err.Error(),
+			ErrorCode:   1,
+		}, params)
+		return errorResponse(event, err, InternalError)
+	}
+	return resultResponse(event, resultMarshal)
+}
+
+// ReportExport handles "ReportExport" events, and returns the stored report
+// rendered in the requested format.
+func ReportExport(
+	ctx context.Context,
+	logger tlog.Logger,
+	reportColl *mongo.Collection,
+	event *model.Event,
+) *model.KafkaResponse {
+	req := reportRequest{}
+	err := unmarshalEventData(event.Data, &req)
+	if err != nil {
+		err = errors.Wrap(err, "ReportExport: Error while unmarshalling Event-data")
+		logger.E(tlog.Entry{
+			Description: err.Error(),
+			ErrorCode:   1,
+		}, string(event.Data))
+		return errorResponse(event, err, ValidationError)
+	}
+
+	err = req.exportOptions.validate()
+	if err != nil {
+		err = errors.Wrap(err, "ReportExport: Invalid export-options")
+		logger.E(tlog.Entry{
+			Description: err.Error(),
+			ErrorCode:   1,
+		}, req)
+		return errorResponse(event, err, ValidationError)
+	}
+
+	rep, errCode, err := findRequestedReport(ctx, reportColl, req)
+	if err != nil {
+		err = errors.Wrap(err, "ReportExport: Error finding report")
+		logger.E(tlog.Entry{
+			Description: err.Error(),
+			ErrorCode:   1,
+		}, req)
+		return errorResponse(event, err, errCode)
+	}
+
+	export, err := exportReport(rep, req.exportOptions)
+	if err != nil {
+		err = errors.Wrap(err, "ReportExport: Error exporting report")
+		logger.E(tlog.Entry{
+			Description: err.Error(),
+			ErrorCode:   1,
+		}, req)
+		return errorResponse(event, err, InternalError)
+	}
+	return resultResponse(event, export)
+}
+
+// exportReport renders the report in the specified format.
+func exportReport(rep *report.SoldReport, opts exportOptions) ([]byte, error) {
+	switch opts.Format {
+	case "", ExportFormatJSON:
+		return json.Marshal(rep)
+	case ExportFormatCSV:
+		csvOpts := report.CSVOptions{}
+		if opts.CSV != nil {
+			csvOpts = *opts.CSV
+		}
+		buf := &bytes.Buffer{}
+		err := report.WriteCSV(buf, rep, csvOpts)
+		if err != nil {
+			return nil, err
+		}
+		return buf.Bytes(), nil
+	case ExportFormatXLSX:
+		buf := &bytes.Buffer{}
+		err := report.WriteXLSX(buf, rep)
+		if err != nil {
+			return nil, err
+		}
+		return buf.Bytes(), nil
+	case ExportFormatHTML:
+		buf := &bytes.Buffer{}
+		err := report.WriteHTML(buf, rep)
+		if err != nil {
+			return nil, err
+		}
+		return buf.Bytes(), nil
+	default:
+		return nil, errors.Errorf("unsupported export format: \"%s\"", opts.Format)
+	}
+}
+
+// exportContentType is the MIME-type of reports exported in the format.
+func exportContentType(format string) string {
+	switch format {
+	case ExportFormatCSV:
+		return "text/csv; charset=utf-8"
+	case ExportFormatXLSX:
+		return "application/vnd.openxmlformats-officedocument.spreadsheetml.sheet"
+	case ExportFormatHTML:
+		return "text/html; charset=utf-8"
+	default:
+		return "application/json"
+	}
+}
+
+// findRequestedReport finds the report specified by the request's reportID.
+// The returned error-code indicates the type of error, if any.
+func findRequestedReport(
+	ctx context.Context,
+	reportColl *mongo.Collection,
+	req reportRequest,
+) (*report.SoldReport, int16, error) {
+	reportID, err := uuuid.FromString(req.ReportID)
+	if err != nil {
+		err = invalidField("reportID", "invalid reportID: %s", err.Error())
+		return nil, ValidationError, err
+	}
+
+	rep, err := report.FindReport(ctx, reportID, reportColl)
+	if err == report.ErrReportNotFound {
+		return nil, NotFoundError, err
+	}
+	if err != nil {
+		return nil, contextErrorCode(err, DatabaseError), err
+	}
+	return rep, 0, nil
+}
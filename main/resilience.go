@@ -0,0 +1,55 @@
+package main
+
+import (
+	"log"
+	"time"
+
+	"github.com/TerrexTech/agg-itemsoldflashsale-report/report"
+)
+
+// Defaults for retrying Mongo-operations and the circuit-breaker, used if
+// the env-vars are not set.
+const (
+	defaultMongoRetryAttempts     = 3
+	defaultMongoRetryBaseDelayMS  = 100
+	defaultMongoRetryMaxDelayMS   = 2000
+	defaultMongoBreakerThreshold  = 5
+	defaultMongoBreakerCooldownMS = 30000
+)
+
+// loadResilience creates the retry-policy and circuit-breaker for
+// Mongo-operations from the MONGO_RETRY_* and MONGO_BREAKER_* env-vars.
+// Retries and circuit-changes are exported as metrics.
+func loadResilience() *report.Resilience {
+	breaker := report.NewCircuitBreaker(
+		loadPositiveInt("MONGO_BREAKER_THRESHOLD", defaultMongoBreakerThreshold),
+		time.Duration(
+			loadPositiveInt("MONGO_BREAKER_COOLDOWN_MS", defaultMongoBreakerCooldownMS),
+		)*time.Millisecond,
+	)
+	breaker.OnStateChange = func(state string) {
+		log.Printf("Mongo circuit-breaker is %s", state)
+		if state == report.CircuitClosed {
+			mongoCircuitOpen.Set(0)
+		} else {
+			mongoCircuitOpen.Set(1)
+		}
+	}
+
+	return &report.Resilience{
+		Retry: report.RetryPolicy{
+			MaxAttempts: loadPositiveInt("MONGO_RETRY_ATTEMPTS", defaultMongoRetryAttempts),
+			BaseDelay: time.Duration(
+				loadPositiveInt("MONGO_RETRY_BASE_DELAY_MS", defaultMongoRetryBaseDelayMS),
+			) * time.Millisecond,
+			MaxDelay: time.Duration(
+				loadPositiveInt("MONGO_RETRY_MAX_DELAY_MS", defaultMongoRetryMaxDelayMS),
+			) * time.Millisecond,
+		},
+		Breaker: breaker,
+		OnRetry: func(attempt int, err error) {
+			log.Printf("Retrying Mongo-operation after attempt %d: %s", attempt, err)
+			mongoRetries.Inc()
+		},
+	}
+}
@@ -0,0 +1,159 @@
+package main
+
+import (
+	"context"
+	"log"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/TerrexTech/go-eventspoll/poll"
+	"github.com/TerrexTech/go-eventstore-models/model"
+	"github.com/TerrexTech/go-mongoutils/mongo"
+	"github.com/pkg/errors"
+	"google.golang.org/grpc"
+)
+
+// defaultShutdownTimeoutMS is how long in-flight events may take to finish
+// on shutdown, if SHUTDOWN_TIMEOUT_MS is not set.
+const defaultShutdownTimeoutMS = 30000
+
+// loadShutdownTimeout reads the shutdown-deadline from SHUTDOWN_TIMEOUT_MS.
+func loadShutdownTimeout() time.Duration {
+	timeoutMS := loadPositiveInt("SHUTDOWN_TIMEOUT_MS", defaultShutdownTimeoutMS)
+	return time.Duration(timeoutMS) * time.Millisecond
+}
+
+// waitContext waits for the WaitGroup, or returns the ctx-error if ctx is
+// done first.
+func waitContext(ctx context.Context, wg *sync.WaitGroup) error {
+	done := make(chan struct{})
+	go func() {
+		wg.Wait()
+		close(done)
+	}()
+
+	select {
+	case <-done:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// service holds the resources released on shutdown. Optional resources,
+// such as the HTTP and gRPC servers, are nil if not started.
+type service struct {
+	eventPoll poll.EventPoll
+	workers   *WorkerPool
+	jobs      *JobManager
+
+	// responses is the input of the response-chunker, and responsesFlushed
+	// is closed once it forwarded all responses to the producer.
+	responses        chan<- *model.KafkaResponse
+	responsesFlushed <-chan struct{}
+
+	deadLetters  *DeadLetterQueue
+	health       *HealthMonitor
+	healthServer *http.Server
+	httpServer   *http.Server
+	grpcServer   *grpc.Server
+	mongoClients []*mongo.Client
+}
+
+// shutdown stops the service in order: the APIs stop accepting requests,
+// in-flight events and report-jobs finish, their responses are flushed to
+// Kafka, and then the Kafka and Mongo clients are closed. Event-consumption
+// must already be stopped. Waiting for in-flight work is bounded by timeout,
+// after which the clients are closed regardless, and an error is returned.
+func (s *service) shutdown(timeout time.Duration) error {
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+
+	var shutdownErr error
+	setErr := func(err error) {
+		log.Println(err)
+		if shutdownErr == nil {
+			shutdownErr = err
+		}
+	}
+
+	// Readiness fails from here on, while liveness and status stay served
+	// until the clients are closed
+	s.health.Drain()
+
+	if s.httpServer != nil {
+		err := s.httpServer.Shutdown(ctx)
+		if err != nil {
+			setErr(errors.Wrap(err, "Error shutting down HTTP server"))
+		}
+	}
+	if s.grpcServer != nil {
+		stopped := make(chan struct{})
+		go func() {
+			s.grpcServer.GracefulStop()
+			close(stopped)
+		}()
+		select {
+		case <-stopped:
+		case <-ctx.Done():
+			s.grpcServer.Stop()
+			setErr(errors.Wrap(ctx.Err(), "Error shutting down gRPC server"))
+		}
+	}
+
+	log.Printf(
+		"Waiting for %d queued and %d in-flight events",
+		s.workers.Queued(), s.workers.InFlight(),
+	)
+	drained := true
+	err := s.workers.Shutdown(ctx)
+	if err != nil {
+		drained = false
+		setErr(errors.Wrap(err, "Error waiting for in-flight events"))
+	}
+	// Jobs still running are cancelled, and don't publish responses anymore
+	err = s.jobs.Shutdown(ctx)
+	if err != nil {
+		setErr(errors.Wrap(err, "Error waiting for report-jobs"))
+	}
+
+	// The responses-channel can only be closed once nothing sends on it
+	if drained {
+		close(s.responses)
+		select {
+		case <-s.responsesFlushed:
+		case <-ctx.Done():
+			setErr(errors.Wrap(ctx.Err(), "Error flushing responses"))
+		}
+	}
+
+	// Closes the Kafka consumers and producers
+	s.eventPoll.Close()
+	if s.deadLetters != nil {
+		err = s.deadLetters.Close()
+		if err != nil {
+			setErr(errors.Wrap(err, "Error closing dead-letter producer"))
+		}
+	}
+
+	err = s.healthServer.Shutdown(ctx)
+	if err != nil {
+		setErr(errors.Wrap(err, "Error shutting down health server"))
+	}
+	err = s.health.Close()
+	if err != nil {
+		setErr(errors.Wrap(err, "Error closing health-check Kafka client"))
+	}
+
+	for _, client := range s.mongoClients {
+		if client == nil {
+			continue
+		}
+		err = client.Disconnect()
+		if err != nil {
+			setErr(errors.Wrap(err, "Error disconnecting MongoClient"))
+		}
+	}
+	return shutdownErr
+}
@@ -0,0 +1,216 @@
+package main
+
+import (
+	"fmt"
+	"log"
+	"os"
+	"time"
+
+	"github.com/Shopify/sarama"
+	"github.com/TerrexTech/agg-itemsoldflashsale-report/report"
+	"github.com/TerrexTech/go-commonutils/commonutil"
+	"github.com/TerrexTech/go-eventspoll/poll"
+	"github.com/TerrexTech/go-kafkautils/kafka"
+	tlog "github.com/TerrexTech/go-logtransport/log"
+	"github.com/TerrexTech/go-mongoutils/mongo"
+	"github.com/mongodb/mongo-go-driver/mongo/findopt"
+	"github.com/pkg/errors"
+)
+
+// Defaults for waiting on dependencies at startup, used if the env-vars are
+// not set.
+const (
+	defaultStartupTimeoutMS       = 60000
+	defaultStartupRetryIntervalMS = 2000
+)
+
+// dependencies are the clients connected at startup.
+type dependencies struct {
+	kafkaConfig  *poll.KafkaConfig
+	logger       tlog.Logger
+	mongoConfig  *poll.MongoConfig
+	mongoClient  *mongo.Client
+	itemSoldColl *mongo.Collection
+	eventPoll    poll.EventPoll
+}
+
+// startupStep is a dependency-check run at startup. Steps run in order,
+// since later steps use the clients connected by earlier ones.
+type startupStep struct {
+	name string
+	run  func() error
+}
+
+// connectDependencies connects to Kafka and Mongo, creates the Logger, and
+// checks that the required topics, collections and indexes exist. A failing step is
+// retried every STARTUP_RETRY_INTERVAL_MS until it passes. If that takes
+// longer than STARTUP_TIMEOUT_MS, a single error names the failing step.
+// Invalid config isn't retried, and is returned right away.
+func connectDependencies() (*dependencies, error) {
+	timeout := time.Duration(
+		loadPositiveInt("STARTUP_TIMEOUT_MS", defaultStartupTimeoutMS),
+	) * time.Millisecond
+	interval := time.Duration(
+		loadPositiveInt("STARTUP_RETRY_INTERVAL_MS", defaultStartupRetryIntervalMS),
+	) * time.Millisecond
+
+	kafkaConfig, err := loadKafkaConfig()
+	if err != nil {
+		err = errors.Wrap(err, "Error loading Kafka config")
+		return nil, err
+	}
+
+	deps := &dependencies{
+		kafkaConfig: kafkaConfig,
+	}
+	steps := []startupStep{
+		{
+			name: "Kafka topics",
+			run:  checkKafkaTopics,
+		},
+		{
+			name: "Logger",
+			run: func() error {
+				prodConfig := &kafka.ProducerConfig{
+					KafkaBrokers: *commonutil.ParseHosts(os.Getenv("KAFKA_BROKERS")),
+				}
+				logger, err := tlog.Init(
+					nil,
+					os.Getenv("SERVICE_NAME"),
+					prodConfig,
+					os.Getenv("KAFKA_LOG_PRODUCER_TOPIC"),
+				)
+				if err != nil {
+					return errors.Wrap(err, "Error initializing Logger")
+				}
+				deps.logger = logger
+				return nil
+			},
+		},
+		{
+			name: "Mongo report-collection",
+			run: func() error {
+				mc, err := loadMongoConfig(
+					os.Getenv("MONGO_REPORT_COLLECTION"), &report.SoldReport{},
+				)
+				deps.mongoConfig = mc
+				return err
+			},
+		},
+		{
+			name: "Mongo sold-item collection",
+			run: func() error {
+				client, err := CreateClient()
+				if err != nil {
+					return errors.Wrap(err, "Error creating MongoClient")
+				}
+				coll, err := CreateCollection(
+					client, os.Getenv("MONGO_AGG_COLLECTION"), &report.FlashSaleSoldItem{},
+				)
+				if err != nil {
+					client.Disconnect()
+					return errors.Wrap(err, "Error creating MongoCollection")
+				}
+				deps.mongoClient = client
+				deps.itemSoldColl = coll
+				return nil
+			},
+		},
+		{
+			name: "Mongo queries",
+			run: func() error {
+				err := pingCollection(deps.mongoConfig.AggCollection)
+				if err != nil {
+					return err
+				}
+				return pingCollection(deps.itemSoldColl)
+			},
+		},
+		{
+			name: "EventPoll",
+			run: func() error {
+				eventPoll, err := poll.Init(poll.IOConfig{
+					ReadConfig: poll.ReadConfig{
+						EnableQuery: true,
+					},
+					KafkaConfig: *deps.kafkaConfig,
+					MongoConfig: *deps.mongoConfig,
+				})
+				if err != nil {
+					return errors.Wrap(err, "Error creating EventPoll service")
+				}
+				deps.eventPoll = eventPoll
+				return nil
+			},
+		},
+	}
+
+	deadline := time.Now().Add(timeout)
+	for _, step := range steps {
+		for attempt := 1; ; attempt++ {
+			err := step.run()
+			if err == nil {
+				log.Printf("Startup: %s ready", step.name)
+				break
+			}
+			if time.Now().Add(interval).After(deadline) {
+				err = errors.Wrapf(
+					err, "%s not ready after %d attempts within %s", step.name, attempt, timeout,
+				)
+				return nil, err
+			}
+			log.Printf("Startup: %s not ready, retrying in %s: %s", step.name, interval, err)
+			time.Sleep(interval)
+		}
+	}
+	return deps, nil
+}
+
+// checkKafkaTopics checks that the brokers are reachable, and that the
+// topics consumed by this service exist. Produced topics, such as for
+// responses, logs and dead-letters, are created by Kafka on first use.
+func checkKafkaTopics() error {
+	brokers := *commonutil.ParseHosts(os.Getenv("KAFKA_BROKERS"))
+	config := sarama.NewConfig()
+	config.Version = sarama.V2_0_0_0
+	config.Metadata.Retry.Max = 1
+	kafkaClient, err := sarama.NewClient(brokers, config)
+	if err != nil {
+		return errors.Wrap(err, "Error connecting to Kafka")
+	}
+	defer kafkaClient.Close()
+
+	topics, err := kafkaClient.Topics()
+	if err != nil {
+		return errors.Wrap(err, "Error listing Kafka topics")
+	}
+	existing := map[string]bool{}
+	for _, topic := range topics {
+		existing[topic] = true
+	}
+
+	missing := []string{}
+	for _, envVar := range []string{
+		"KAFKA_CONSUMER_EVENT_TOPIC",
+		"KAFKA_CONSUMER_EVENT_QUERY_TOPIC",
+	} {
+		topic := os.Getenv(envVar)
+		if topic != "" && !existing[topic] {
+			missing = append(missing, fmt.Sprintf("%s (%s)", topic, envVar))
+		}
+	}
+	if len(missing) > 0 {
+		return errors.Errorf("missing Kafka topics: %v", missing)
+	}
+	return nil
+}
+
+// pingCollection runs a minimal query, to check that the collection can be
+// read.
+func pingCollection(coll *mongo.Collection) error {
+	_, err := coll.Find(map[string]interface{}{}, findopt.Limit(1))
+	if err != nil {
+		return errors.Wrapf(err, "Error querying collection %s", coll.Name)
+	}
+	return nil
+}
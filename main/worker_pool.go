@@ -0,0 +1,152 @@
+package main
+
+import (
+	"context"
+	"log"
+	"os"
+	"strconv"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/TerrexTech/go-eventstore-models/model"
+	"github.com/pkg/errors"
+)
+
+// Defaults for the worker-pool, used if the env-vars are not set.
+const (
+	defaultWorkerPoolSize  = 8
+	defaultWorkerQueueSize = 100
+)
+
+// loadPositiveInt reads a positive integer from the env-var, or returns the
+// default value if the env-var is not set or invalid.
+func loadPositiveInt(envVar string, defaultValue int) int {
+	valueStr := os.Getenv(envVar)
+	if valueStr == "" {
+		return defaultValue
+	}
+	value, err := strconv.Atoi(valueStr)
+	if err != nil || value <= 0 {
+		err = errors.Errorf("Invalid %s: \"%s\"", envVar, valueStr)
+		log.Println(err)
+		log.Printf("A default value of %d will be used for %s", defaultValue, envVar)
+		return defaultValue
+	}
+	return value
+}
+
+// loadWorkerPoolConfig reads the number of workers from WORKER_POOL_SIZE,
+// and the capacity of the worker-queue from WORKER_QUEUE_SIZE.
+func loadWorkerPoolConfig() (size int, queueSize int) {
+	size = loadPositiveInt("WORKER_POOL_SIZE", defaultWorkerPoolSize)
+	queueSize = loadPositiveInt("WORKER_QUEUE_SIZE", defaultWorkerQueueSize)
+	return size, queueSize
+}
+
+// queuedEvent is an event waiting in the worker-queue.
+type queuedEvent struct {
+	event    *model.Event
+	queuedAt time.Time
+}
+
+// WorkerPool handles events using a fixed number of workers. Events wait in
+// a bounded queue until a worker is free. Once the queue is full, Submit
+// blocks, so the caller stops consuming events until workers catch up.
+type WorkerPool struct {
+	handler func(event *model.Event)
+	queue   chan queuedEvent
+	wg      sync.WaitGroup
+
+	queued   int64
+	inFlight int64
+}
+
+// NewWorkerPool starts size workers, which run the handler for each event
+// submitted to the pool. At most queueSize events wait for a free worker.
+func NewWorkerPool(
+	size int,
+	queueSize int,
+	handler func(event *model.Event),
+) *WorkerPool {
+	p := &WorkerPool{
+		handler: handler,
+		queue:   make(chan queuedEvent, queueSize),
+	}
+	p.wg.Add(size)
+	for i := 0; i < size; i++ {
+		go p.work()
+	}
+	return p
+}
+
+// Submit queues the event for the workers. If the queue is full, Submit
+// blocks until a worker is free, or returns the ctx-error if ctx is done first.
+func (p *WorkerPool) Submit(ctx context.Context, event *model.Event) error {
+	qe := queuedEvent{
+		event:    event,
+		queuedAt: time.Now(),
+	}
+	// Counted before sending, so workers never see a negative count
+	p.addQueued(1)
+	select {
+	case p.queue <- qe:
+		return nil
+	default:
+	}
+
+	eventQueueFull.Inc()
+	log.Printf(
+		"Worker-queue full (%d events), pausing event-consumption",
+		cap(p.queue),
+	)
+	select {
+	case p.queue <- qe:
+		log.Println("Worker-queue has capacity, resuming event-consumption")
+		return nil
+	case <-ctx.Done():
+		p.addQueued(-1)
+		return ctx.Err()
+	}
+}
+
+// Shutdown stops accepting events, and waits until the workers handled all
+// queued events, or returns the ctx-error if ctx is done first.
+// Submit must not be called after Shutdown.
+func (p *WorkerPool) Shutdown(ctx context.Context) error {
+	close(p.queue)
+	return waitContext(ctx, &p.wg)
+}
+
+// Queued returns the number of events waiting for a worker.
+func (p *WorkerPool) Queued() int64 {
+	return atomic.LoadInt64(&p.queued)
+}
+
+// InFlight returns the number of events being handled by workers.
+func (p *WorkerPool) InFlight() int64 {
+	return atomic.LoadInt64(&p.inFlight)
+}
+
+func (p *WorkerPool) addQueued(delta int64) {
+	eventQueueDepth.Set(float64(atomic.AddInt64(&p.queued, delta)))
+}
+
+func (p *WorkerPool) addInFlight(delta int64) {
+	eventsInFlight.Set(float64(atomic.AddInt64(&p.inFlight, delta)))
+}
+
+func (p *WorkerPool) work() {
+	defer p.wg.Done()
+
+	for qe := range p.queue {
+		p.addQueued(-1)
+		eventQueueWait.
+			WithLabelValues(actionLabel(qe.event.ServiceAction)).
+			Observe(time.Since(qe.queuedAt).Seconds())
+
+		p.addInFlight(1)
+		p.handler(qe.event)
+		p.addInFlight(-1)
+	}
+}
@@ -0,0 +1,255 @@
+package report
+
+import (
+	"fmt"
+	"time"
+
+	util "github.com/TerrexTech/go-commonutils/commonutil"
+	"github.com/TerrexTech/uuuid"
+	"github.com/pkg/errors"
+)
+
+// AggregateRow is a single output-row of a sold-item aggregation-pipeline.
+// Dimensions not used for grouping are left blank.
+type AggregateRow struct {
+	SKU         string
+	Name        string
+	Lot         string
+	Bucket      int64
+	SoldWeight  float64
+	TotalWeight float64
+	// Count is the number of sold-items in the group.
+	Count int64
+}
+
+// RowError describes an aggregation-row that could not be decoded.
+// Index is the position of the row in the aggregation-output.
+type RowError struct {
+	Index int    `json:"index"`
+	Error string `json:"error"`
+}
+
+// Statuses of QueryResults.
+const (
+	// ReportStatusComplete is when the report was computed and stored.
+	ReportStatusComplete = "complete"
+	// ReportStatusPartial is when the report was computed, but not stored,
+	// such as when storing it failed, so it can't be looked up by its ReportID.
+	ReportStatusPartial = "partial"
+)
+
+// QueryResult is the response-payload for a sold-item query. Empty is set
+// if no sold-items matched the query, in which case the rows and totals are
+// zero.
+type QueryResult struct {
+	ReportID     uuuid.UUID     `json:"reportID,omitempty"`
+	Status       string         `json:"status"`
+	ReportResult []ReportResult `json:"reportResult"`
+	// Totals are not set for averaged reports without row-counts.
+	Totals    *ReportTotals `json:"totals,omitempty"`
+	Empty     bool          `json:"empty"`
+	RowErrors []RowError    `json:"rowErrors,omitempty"`
+	// StoreError is why storing the report failed, if Status is partial.
+	StoreError string `json:"storeError,omitempty"`
+}
+
+// NewSoldReport creates the report of the aggregation-output, aggregated
+// using spec from the sold-items matching the search-params. Rows that can't
+// be decoded are left out of the report, and returned as RowErrors.
+func NewSoldReport(
+	reportID uuuid.UUID,
+	params SoldItemParams,
+	spec AggregationSpec,
+	aggResult []interface{},
+) (SoldReport, []RowError) {
+	rows, rowErrors := DecodeAggregateRows(aggResult)
+	// Windows without sold-items are valid reports, with zero rows. They are
+	// flagged, so they can be told apart from reports whose rows all failed
+	// to decode.
+	return SoldReport{
+		ReportID:     reportID,
+		SearchQuery:  params,
+		ReportResult: rows,
+		Timestamp:    time.Now().Unix(),
+		Empty:        len(aggResult) == 0,
+		Metric:       spec.Metric,
+	}, rowErrors
+}
+
+// NewQueryResult creates the QueryResult of the stored report. Its Status
+// has to be changed if the report wasn't stored.
+func NewQueryResult(rep SoldReport, rowErrors []RowError) *QueryResult {
+	return &QueryResult{
+		ReportID:     rep.ReportID,
+		Status:       ReportStatusComplete,
+		ReportResult: rep.ReportResult,
+		Totals:       NewReportTotals(rep.ReportResult, rep.Metric),
+		Empty:        rep.Empty,
+		RowErrors:    rowErrors,
+	}
+}
+
+// ReportTotals are the summed weights of the report-rows. Unlike
+// ReportResult, zero-values are kept when marshalled.
+type ReportTotals struct {
+	Rows        int     `json:"rows"`
+	SoldWeight  float64 `json:"soldWeight"`
+	TotalWeight float64 `json:"totalWeight"`
+}
+
+// NewReportTotals sums the weights of the rows. Rows aggregated using
+// MetricSum are summed as-is. Averaged rows are multiplied by the number of
+// sold-items they average, which gives the same totals. Averages can't be
+// summed without their counts, such as for reports stored before rows were
+// counted, so nil is returned for them. A blank metric is MetricAvg, the
+// default.
+func NewReportTotals(rows []ReportResult, metric string) *ReportTotals {
+	totals := &ReportTotals{
+		Rows: len(rows),
+	}
+	for _, r := range rows {
+		if metric == MetricSum {
+			totals.SoldWeight += r.SoldWeight
+			totals.TotalWeight += r.TotalWeight
+			continue
+		}
+		if r.Count <= 0 {
+			return nil
+		}
+		totals.SoldWeight += r.SoldWeight * float64(r.Count)
+		totals.TotalWeight += r.TotalWeight * float64(r.Count)
+	}
+	return totals
+}
+
+// SellThrough is the ratio of the total sold weight to the total weight.
+func (t ReportTotals) SellThrough() float64 {
+	if t.TotalWeight == 0 {
+		return 0
+	}
+	return t.SoldWeight / t.TotalWeight
+}
+
+// DecodeAggregateRow decodes a raw document from the aggregation-output
+// into an AggregateRow. Numeric fields are coerced to float64, so rows
+// with integer-typed weights are decoded as well.
+func DecodeAggregateRow(v interface{}) (AggregateRow, error) {
+	row := AggregateRow{}
+
+	m, assertOK := v.(map[string]interface{})
+	if !assertOK {
+		return row, fmt.Errorf("expected document, got %T", v)
+	}
+
+	groupByFields, assertOK := m["_id"].(map[string]interface{})
+	if !assertOK {
+		return row, fmt.Errorf("expected document for _id, got %T", m["_id"])
+	}
+
+	var err error
+	row.SKU, err = assertDimension(groupByFields, DimensionSKU, true)
+	if err != nil {
+		return row, err
+	}
+	row.Lot, err = assertDimension(groupByFields, DimensionLot, true)
+	if err != nil {
+		return row, err
+	}
+	// Name is informational, so a missing name is left blank
+	row.Name, err = assertDimension(groupByFields, DimensionName, false)
+	if err != nil {
+		return row, err
+	}
+
+	if _, ok := groupByFields["bucket"]; ok {
+		row.Bucket, err = util.AssertInt64(groupByFields["bucket"])
+		if err != nil {
+			err = errors.Wrap(err, "Error while asserting bucket")
+			return row, err
+		}
+	}
+
+	row.SoldWeight, err = assertMetric(m, "sold")
+	if err != nil {
+		return row, err
+	}
+	row.TotalWeight, err = assertMetric(m, "total")
+	if err != nil {
+		return row, err
+	}
+	// Pipelines of older versions didn't count the sold-items
+	if m["count"] != nil {
+		count, err := util.AssertFloat64(m["count"])
+		if err != nil {
+			err = errors.Wrap(err, "Error while asserting count")
+			return row, err
+		}
+		row.Count = int64(count)
+	}
+	return row, nil
+}
+
+// DecodeAggregateRows decodes the aggregation-output into ReportResults.
+// Rows that cannot be decoded are skipped and returned as RowErrors.
+func DecodeAggregateRows(rows []interface{}) ([]ReportResult, []RowError) {
+	results := make([]ReportResult, 0, len(rows))
+	var rowErrors []RowError
+
+	for i, v := range rows {
+		row, err := DecodeAggregateRow(v)
+		if err != nil {
+			rowErrors = append(rowErrors, RowError{
+				Index: i,
+				Error: err.Error(),
+			})
+			continue
+		}
+		results = append(results, ReportResult{
+			SKU:         row.SKU,
+			Name:        row.Name,
+			Lot:         row.Lot,
+			Bucket:      row.Bucket,
+			SoldWeight:  row.SoldWeight,
+			TotalWeight: row.TotalWeight,
+			Count:       row.Count,
+		})
+	}
+	return results, rowErrors
+}
+
+// assertDimension returns the value of a group-by dimension. Dimensions
+// not grouped by are absent, and are returned blank. If required is true,
+// the dimension cannot be null when present.
+func assertDimension(
+	groupByFields map[string]interface{},
+	dim string,
+	required bool,
+) (string, error) {
+	v, ok := groupByFields[dim]
+	if !ok || (v == nil && !required) {
+		return "", nil
+	}
+	str, assertOK := v.(string)
+	if !assertOK || (str == "" && required) {
+		return "", fmt.Errorf("expected non-empty string for %s, got %T", dim, v)
+	}
+	return str, nil
+}
+
+// assertMetric returns the value of the weight-field ("sold" or "total"),
+// for whichever metric was used in the aggregation.
+func assertMetric(m map[string]interface{}, field string) (float64, error) {
+	for _, metric := range []string{MetricAvg, MetricSum} {
+		key := metric + "_" + field
+		if m[key] == nil {
+			continue
+		}
+		num, err := util.AssertFloat64(m[key])
+		if err != nil {
+			err = errors.Wrapf(err, "Error while asserting %s", key)
+			return 0, err
+		}
+		return num, nil
+	}
+	return 0, fmt.Errorf("missing %s weight", field)
+}
@@ -0,0 +1,229 @@
+package report
+
+import (
+	"encoding/json"
+
+	"github.com/TerrexTech/uuuid"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Aggregate-row decoding", func() {
+	newRow := func(id interface{}, avgSold interface{}, avgTotal interface{}) interface{} {
+		return map[string]interface{}{
+			"_id":       id,
+			"avg_sold":  avgSold,
+			"avg_total": avgTotal,
+		}
+	}
+
+	It("decodes a well-formed row", func() {
+		row, err := DecodeAggregateRow(newRow(
+			map[string]interface{}{
+				"sku":  "test-sku1",
+				"name": "test-name1",
+			},
+			float64(101),
+			float64(120),
+		))
+		Expect(err).ToNot(HaveOccurred())
+		Expect(row).To(Equal(AggregateRow{
+			SKU:         "test-sku1",
+			Name:        "test-name1",
+			SoldWeight:  101,
+			TotalWeight: 120,
+		}))
+	})
+
+	It("coerces integer-typed weights", func() {
+		row, err := DecodeAggregateRow(newRow(
+			map[string]interface{}{
+				"sku":  "test-sku1",
+				"name": "test-name1",
+			},
+			int32(101),
+			int64(120),
+		))
+		Expect(err).ToNot(HaveOccurred())
+		Expect(row.SoldWeight).To(Equal(float64(101)))
+		Expect(row.TotalWeight).To(Equal(float64(120)))
+	})
+
+	It("decodes the count of sold-items", func() {
+		m := newRow(
+			map[string]interface{}{
+				"sku":  "test-sku1",
+				"name": "test-name1",
+			},
+			float64(101),
+			float64(120),
+		).(map[string]interface{})
+		m["count"] = int32(3)
+		row, err := DecodeAggregateRow(m)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(row.Count).To(Equal(int64(3)))
+	})
+
+	It("decodes lot and bucket dimensions with sum metric", func() {
+		row, err := DecodeAggregateRow(map[string]interface{}{
+			"_id": map[string]interface{}{
+				"lot":    "test-lot1",
+				"bucket": int64(86400),
+			},
+			"sum_sold":  float64(206),
+			"sum_total": float64(260),
+		})
+		Expect(err).ToNot(HaveOccurred())
+		Expect(row).To(Equal(AggregateRow{
+			Lot:         "test-lot1",
+			Bucket:      86400,
+			SoldWeight:  206,
+			TotalWeight: 260,
+		}))
+	})
+
+	It("returns error when sku is null", func() {
+		_, err := DecodeAggregateRow(newRow(
+			map[string]interface{}{
+				"sku":  nil,
+				"name": "test-name1",
+			},
+			float64(101),
+			float64(120),
+		))
+		Expect(err).To(HaveOccurred())
+	})
+
+	It("returns error when _id is not a document", func() {
+		_, err := DecodeAggregateRow(newRow("test-sku1", float64(101), float64(120)))
+		Expect(err).To(HaveOccurred())
+	})
+
+	It("returns error when a weight is missing", func() {
+		_, err := DecodeAggregateRow(newRow(
+			map[string]interface{}{
+				"sku": "test-sku1",
+			},
+			float64(101),
+			nil,
+		))
+		Expect(err).To(HaveOccurred())
+	})
+
+	It("reports malformed rows without dropping valid ones", func() {
+		rows := []interface{}{
+			newRow(
+				map[string]interface{}{
+					"sku":  "test-sku1",
+					"name": "test-name1",
+				},
+				float64(101),
+				float64(120),
+			),
+			"not-a-row",
+			newRow(
+				map[string]interface{}{
+					"sku": nil,
+				},
+				float64(105),
+				float64(140),
+			),
+		}
+
+		results, rowErrors := DecodeAggregateRows(rows)
+		Expect(results).To(HaveLen(1))
+		Expect(results[0].SKU).To(Equal("test-sku1"))
+
+		Expect(rowErrors).To(HaveLen(2))
+		Expect(rowErrors[0].Index).To(Equal(1))
+		Expect(rowErrors[1].Index).To(Equal(2))
+	})
+	It("marshals zero totals of empty results", func() {
+		rows, rowErrors := DecodeAggregateRows([]interface{}{})
+		Expect(rowErrors).To(BeEmpty())
+
+		resultMarshal, err := json.Marshal(QueryResult{
+			ReportResult: rows,
+			Totals:       NewReportTotals(rows, MetricSum),
+			Empty:        true,
+		})
+		Expect(err).ToNot(HaveOccurred())
+
+		result := map[string]interface{}{}
+		err = json.Unmarshal(resultMarshal, &result)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(result["reportResult"]).To(Equal([]interface{}{}))
+		Expect(result["totals"]).To(Equal(map[string]interface{}{
+			"rows":        float64(0),
+			"soldWeight":  float64(0),
+			"totalWeight": float64(0),
+		}))
+		Expect(result["empty"]).To(BeTrue())
+	})
+
+	It("sums the totals of the rows", func() {
+		Expect(NewReportTotals([]ReportResult{
+			ReportResult{SoldWeight: 2, TotalWeight: 4},
+			ReportResult{SoldWeight: 3, TotalWeight: 6},
+		}, MetricSum)).To(Equal(&ReportTotals{
+			Rows:        2,
+			SoldWeight:  5,
+			TotalWeight: 10,
+		}))
+	})
+
+	It("sums averaged rows using their counts", func() {
+		Expect(NewReportTotals([]ReportResult{
+			ReportResult{SoldWeight: 2, TotalWeight: 4, Count: 3},
+			ReportResult{SoldWeight: 3, TotalWeight: 6, Count: 1},
+		}, MetricAvg)).To(Equal(&ReportTotals{
+			Rows:        2,
+			SoldWeight:  9,
+			TotalWeight: 18,
+		}))
+	})
+
+	It("does not sum averaged rows without counts", func() {
+		rows := []ReportResult{
+			ReportResult{SoldWeight: 2, TotalWeight: 4},
+		}
+		Expect(NewReportTotals(rows, MetricAvg)).To(BeNil())
+		Expect(NewReportTotals(rows, "")).To(BeNil())
+
+		resultMarshal, err := json.Marshal(QueryResult{
+			ReportResult: rows,
+			Totals:       NewReportTotals(rows, ""),
+		})
+		Expect(err).ToNot(HaveOccurred())
+		Expect(string(resultMarshal)).ToNot(ContainSubstring("totals"))
+	})
+
+	It("creates reports and results with their metric and empty-flag", func() {
+		reportID, err := uuuid.NewV4()
+		Expect(err).ToNot(HaveOccurred())
+		spec := AggregationSpec{Metric: MetricSum}
+
+		rep, rowErrors := NewSoldReport(reportID, SoldItemParams{}, spec, []interface{}{})
+		Expect(rowErrors).To(BeEmpty())
+		Expect(rep.ReportID).To(Equal(reportID))
+		Expect(rep.Metric).To(Equal(MetricSum))
+		Expect(rep.Empty).To(BeTrue())
+		Expect(rep.Timestamp).ToNot(BeZero())
+
+		result := NewQueryResult(rep, rowErrors)
+		Expect(result.ReportID).To(Equal(reportID))
+		Expect(result.Status).To(Equal(ReportStatusComplete))
+		Expect(result.Empty).To(BeTrue())
+		Expect(result.Totals).To(Equal(&ReportTotals{}))
+	})
+
+	It("doesn't flag reports whose rows failed to decode as empty", func() {
+		rep, rowErrors := NewSoldReport(
+			uuuid.UUID{}, SoldItemParams{}, DefaultAggregation(), []interface{}{"not-a-row"},
+		)
+		Expect(rowErrors).To(HaveLen(1))
+		Expect(rep.ReportResult).To(BeEmpty())
+		Expect(rep.Empty).To(BeFalse())
+	})
+})
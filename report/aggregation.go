@@ -0,0 +1,268 @@
+package report
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+
+	"github.com/TerrexTech/go-mongoutils/mongo"
+	"github.com/mongodb/mongo-go-driver/bson"
+	"github.com/pkg/errors"
+)
+
+// Dimensions by which sold-items can be grouped.
+const (
+	DimensionSKU  = "sku"
+	DimensionName = "name"
+	DimensionLot  = "lot"
+)
+
+// Metrics for summarizing the weights of grouped sold-items.
+const (
+	MetricAvg = "avg"
+	MetricSum = "sum"
+)
+
+// Time-buckets for grouping sold-items by their timestamp.
+const (
+	BucketHour = "hour"
+	BucketDay  = "day"
+	BucketWeek = "week"
+)
+
+// Fields by which the aggregation-output can be sorted.
+const (
+	SortSoldWeight  = "soldWeight"
+	SortTotalWeight = "totalWeight"
+	SortBucket      = "bucket"
+)
+
+var bucketSeconds = map[string]int64{
+	BucketHour: 3600,
+	BucketDay:  86400,
+	BucketWeek: 604800,
+}
+
+// AggregationSpec describes how sold-items are grouped and summarized.
+type AggregationSpec struct {
+	// GroupBy are the dimensions to group by. Defaults to SKU and Name.
+	GroupBy []string `json:"groupBy,omitempty"`
+	// Metric is applied to sold and total weights. Defaults to MetricAvg.
+	Metric string `json:"metric,omitempty"`
+	// Bucket additionally groups the sold-items by time-bucket.
+	// Buckets are aligned to unix-epoch, in UTC.
+	Bucket string `json:"bucket,omitempty"`
+	// SortBy sorts the output in descending order, except for SortBucket,
+	// which sorts in ascending order.
+	SortBy string `json:"sortBy,omitempty"`
+	// Limit limits the number of output-rows, if greater than 0.
+	Limit int64 `json:"limit,omitempty"`
+}
+
+// DefaultAggregation is the aggregation used for sold-item reports.
+func DefaultAggregation() AggregationSpec {
+	return AggregationSpec{
+		GroupBy: []string{DimensionSKU, DimensionName},
+		Metric:  MetricAvg,
+	}
+}
+
+// withDefaults returns a copy of the spec with blank fields set to defaults.
+func (a AggregationSpec) withDefaults() AggregationSpec {
+	def := DefaultAggregation()
+	if len(a.GroupBy) == 0 && a.Bucket == "" {
+		a.GroupBy = def.GroupBy
+	}
+	if a.Metric == "" {
+		a.Metric = def.Metric
+	}
+	return a
+}
+
+// Validate checks the spec for unknown dimensions, metrics, buckets and
+// sort-fields.
+func (a AggregationSpec) Validate() error {
+	a = a.withDefaults()
+	fields := fieldErrors{}
+
+	for i, dim := range a.GroupBy {
+		switch dim {
+		case DimensionSKU, DimensionName, DimensionLot:
+		default:
+			fields.add(
+				fmt.Sprintf("groupBy[%d]", i), "unknown groupBy dimension: \"%s\"", dim,
+			)
+		}
+	}
+	if a.Metric != MetricAvg && a.Metric != MetricSum {
+		fields.add("metric", "unknown metric: \"%s\"", a.Metric)
+	}
+	if _, ok := bucketSeconds[a.Bucket]; a.Bucket != "" && !ok {
+		fields.add("bucket", "unknown bucket: \"%s\"", a.Bucket)
+	}
+	switch a.SortBy {
+	case "", SortSoldWeight, SortTotalWeight:
+	case SortBucket:
+		if a.Bucket == "" {
+			fields.add("sortBy", "sortBy bucket requires a bucket")
+		}
+	default:
+		fields.add("sortBy", "unknown sortBy field: \"%s\"", a.SortBy)
+	}
+	if a.Limit < 0 {
+		fields.add("limit", "limit cannot be negative")
+	}
+	return fields.err()
+}
+
+// Pipeline builds the aggregation-pipeline for the search-params.
+func (a AggregationSpec) Pipeline(params SoldItemParams) (*bson.Array, error) {
+	err := a.Validate()
+	if err != nil {
+		return nil, err
+	}
+	a = a.withDefaults()
+
+	groupID := map[string]interface{}{}
+	for _, dim := range a.GroupBy {
+		groupID[dim] = "$" + dim
+	}
+	if a.Bucket != "" {
+		size := bucketSeconds[a.Bucket]
+		groupID["bucket"] = map[string]interface{}{
+			"$subtract": []interface{}{
+				"$timestamp",
+				map[string]interface{}{
+					"$mod": []interface{}{"$timestamp", size},
+				},
+			},
+		}
+	}
+
+	soldField := a.Metric + "_sold"
+	totalField := a.Metric + "_total"
+	op := "$" + a.Metric
+	pipeline := []interface{}{
+		map[string]interface{}{
+			"$match": params,
+		},
+		map[string]interface{}{
+			"$group": map[string]interface{}{
+				"_id": groupID,
+				soldField: map[string]interface{}{
+					op: "$weight",
+				},
+				totalField: map[string]interface{}{
+					op: "$totalWeight",
+				},
+				// Averages can be summed using the number of sold-items
+				"count": map[string]interface{}{
+					"$sum": 1,
+				},
+			},
+		},
+	}
+
+	var sort map[string]interface{}
+	switch a.SortBy {
+	case SortSoldWeight:
+		sort = map[string]interface{}{soldField: -1}
+	case SortTotalWeight:
+		sort = map[string]interface{}{totalField: -1}
+	case SortBucket:
+		sort = map[string]interface{}{"_id.bucket": 1}
+	}
+	if sort != nil {
+		pipeline = append(pipeline, map[string]interface{}{
+			"$sort": sort,
+		})
+	}
+	if a.Limit > 0 {
+		pipeline = append(pipeline, map[string]interface{}{
+			"$limit": a.Limit,
+		})
+	}
+
+	pipelineJSON, err := json.Marshal(pipeline)
+	if err != nil {
+		err = errors.Wrap(err, "Unable to marshal pipeline")
+		return nil, err
+	}
+
+	pipelineAgg, err := bson.ParseExtJSONArray(string(pipelineJSON))
+	if err != nil {
+		err = errors.Wrap(err, "Error in parsing pipeline")
+		return nil, err
+	}
+	return pipelineAgg, nil
+}
+
+// aggregationSlots bounds the number of aggregations running at once. It is
+// nil, and aggregations are unbounded, until SetAggregationLimit is called.
+var aggregationSlots chan struct{}
+
+// SetAggregationLimit limits the number of aggregations running at once,
+// across all callers. Further aggregations wait for a free slot. It should
+// be called before any aggregation runs.
+func SetAggregationLimit(limit int) {
+	aggregationSlots = make(chan struct{}, limit)
+}
+
+// acquireAggregationSlot waits for a free aggregation-slot, and returns the
+// func releasing it, or returns the ctx-error if ctx is done first.
+func acquireAggregationSlot(ctx context.Context) (func(), error) {
+	slots := aggregationSlots
+	if slots == nil {
+		return func() {}, nil
+	}
+	select {
+	case slots <- struct{}{}:
+		return func() { <-slots }, nil
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+}
+
+// Aggregate runs the aggregation described by spec on sold-items matching
+// the search-params. It waits for a free slot if the aggregation-limit is
+// reached. If ctx is done before the aggregation completes, the ctx-error
+// is returned.
+func Aggregate(
+	ctx context.Context,
+	params SoldItemParams,
+	spec AggregationSpec,
+	itemSoldColl *mongo.Collection,
+) ([]interface{}, error) {
+	err := params.Validate()
+	if err != nil {
+		log.Println(err)
+		return nil, err
+	}
+
+	pipelineAgg, err := spec.Pipeline(params)
+	if err != nil {
+		err = errors.Wrap(err, "Query: Error in generating pipeline for report")
+		log.Println(err)
+		return nil, err
+	}
+
+	release, err := acquireAggregationSlot(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer release()
+
+	var findResult []interface{}
+	err = runMongoOp(ctx, func() error {
+		var err error
+		findResult, err = itemSoldColl.Aggregate(pipelineAgg)
+		return err
+	})
+	if err != nil {
+		err = errors.Wrap(err, "Query: Error in getting aggregate results ")
+		log.Println(err)
+		return nil, err
+	}
+	return findResult, nil
+}
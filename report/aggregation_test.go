@@ -0,0 +1,114 @@
+package report
+
+import (
+	"context"
+
+	"github.com/pkg/errors"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Aggregation spec", func() {
+	It("accepts the default aggregation", func() {
+		Expect(DefaultAggregation().Validate()).To(Succeed())
+		Expect(AggregationSpec{}.Validate()).To(Succeed())
+	})
+
+	It("accepts a bucketed trend", func() {
+		spec := AggregationSpec{
+			Metric: MetricSum,
+			Bucket: BucketDay,
+			SortBy: SortBucket,
+		}
+		Expect(spec.Validate()).To(Succeed())
+	})
+
+	It("returns error for unknown dimensions, metrics and buckets", func() {
+		Expect(AggregationSpec{GroupBy: []string{"color"}}.Validate()).ToNot(Succeed())
+		Expect(AggregationSpec{Metric: "median"}.Validate()).ToNot(Succeed())
+		Expect(AggregationSpec{Bucket: "fortnight"}.Validate()).ToNot(Succeed())
+		Expect(AggregationSpec{SortBy: "name"}.Validate()).ToNot(Succeed())
+	})
+
+	It("returns error when sorting by bucket without a bucket", func() {
+		Expect(AggregationSpec{SortBy: SortBucket}.Validate()).ToNot(Succeed())
+	})
+})
+
+var _ = Describe("Aggregation limit", func() {
+	AfterEach(func() {
+		aggregationSlots = nil
+	})
+
+	It("doesn't wait while no limit is set", func() {
+		release, err := acquireAggregationSlot(context.Background())
+		Expect(err).ToNot(HaveOccurred())
+		release()
+	})
+
+	It("waits for a released slot once the limit is reached", func() {
+		SetAggregationLimit(1)
+		release, err := acquireAggregationSlot(context.Background())
+		Expect(err).ToNot(HaveOccurred())
+
+		ctx, cancel := context.WithCancel(context.Background())
+		cancel()
+		_, err = acquireAggregationSlot(ctx)
+		Expect(err).To(Equal(context.Canceled))
+
+		release()
+		release, err = acquireAggregationSlot(context.Background())
+		Expect(err).ToNot(HaveOccurred())
+		release()
+	})
+})
+
+var _ = Describe("Batch validation", func() {
+	It("returns error for empty batches", func() {
+		Expect(ValidateBatch([]SubQuery{})).ToNot(Succeed())
+	})
+
+	It("returns error for unnamed or duplicate sub-queries", func() {
+		Expect(ValidateBatch([]SubQuery{
+			SubQuery{Name: ""},
+		})).ToNot(Succeed())
+
+		Expect(ValidateBatch([]SubQuery{
+			SubQuery{Name: "topSkus"},
+			SubQuery{Name: "topSkus"},
+		})).ToNot(Succeed())
+	})
+
+	It("returns error when batch is too large", func() {
+		queries := []SubQuery{}
+		for i := 0; i <= MaxBatchQueries; i++ {
+			queries = append(queries, SubQuery{
+				Name: string(rune('a' + i)),
+			})
+		}
+		Expect(ValidateBatch(queries)).ToNot(Succeed())
+	})
+})
+
+var _ = Describe("Batch results", func() {
+	It("flags sub-queries without matching sold-items as empty", func() {
+		result := newSubQueryResult("none", []interface{}{}, nil)
+		Expect(result.Empty).To(BeTrue())
+		Expect(result.ReportResult).To(BeEmpty())
+		Expect(result.RowErrors).To(BeEmpty())
+	})
+
+	It("doesn't flag sub-queries whose rows failed to decode as empty", func() {
+		result := newSubQueryResult("invalid", []interface{}{"not a row"}, nil)
+		Expect(result.Empty).To(BeFalse())
+		Expect(result.ReportResult).To(BeEmpty())
+		Expect(result.RowErrors).To(HaveLen(1))
+	})
+
+	It("sets the error of failed sub-queries", func() {
+		result := newSubQueryResult("failed", nil, errors.New("aggregation failed"))
+		Expect(result.Error).To(Equal("aggregation failed"))
+		Expect(result.Empty).To(BeFalse())
+	})
+})
@@ -0,0 +1,105 @@
+package report
+
+import (
+	"context"
+	"fmt"
+	"sync"
+
+	"github.com/TerrexTech/go-mongoutils/mongo"
+	"github.com/pkg/errors"
+)
+
+// MaxBatchQueries is the maximum number of sub-queries in a batch.
+const MaxBatchQueries = 10
+
+// SubQuery is a named query in a batch. Each sub-query is aggregated
+// independently.
+type SubQuery struct {
+	Name        string          `json:"name"`
+	Params      SoldItemParams  `json:"params"`
+	Aggregation AggregationSpec `json:"aggregation,omitempty"`
+}
+
+// SubQueryResult is the result of a SubQuery. Error is set if the sub-query
+// failed, in which case there are no results. Empty is set if no sold-items
+// matched the sub-query, which tells it apart from sub-queries whose rows
+// all failed to decode.
+type SubQueryResult struct {
+	Name         string         `json:"name"`
+	ReportResult []ReportResult `json:"reportResult,omitempty"`
+	Empty        bool           `json:"empty"`
+	RowErrors    []RowError     `json:"rowErrors,omitempty"`
+	Error        string         `json:"error,omitempty"`
+}
+
+// ErrBatchTooLarge is returned when a batch has more than MaxBatchQueries
+// sub-queries.
+var ErrBatchTooLarge = errors.Errorf(
+	"batch contains more than %d sub-queries", MaxBatchQueries,
+)
+
+// ValidateBatch checks that the batch is not empty or too large, and that
+// sub-query names are non-empty and unique.
+func ValidateBatch(queries []SubQuery) error {
+	if len(queries) > MaxBatchQueries {
+		return errors.Wrapf(ErrBatchTooLarge, "batch contains %d sub-queries", len(queries))
+	}
+
+	fields := fieldErrors{}
+	if len(queries) == 0 {
+		fields.add("", "batch contains no sub-queries")
+	}
+	names := map[string]bool{}
+	for i, q := range queries {
+		field := fmt.Sprintf("[%d].name", i)
+		if q.Name == "" {
+			fields.add(field, "sub-query at index %d has no name", i)
+			continue
+		}
+		if names[q.Name] {
+			fields.add(field, "duplicate sub-query name: \"%s\"", q.Name)
+		}
+		names[q.Name] = true
+	}
+	return fields.err()
+}
+
+// RunBatch runs the sub-queries concurrently. Results are in same order as
+// the sub-queries. Sub-queries still running when ctx is done fail with the
+// ctx-error.
+func RunBatch(
+	ctx context.Context,
+	queries []SubQuery,
+	itemSoldColl *mongo.Collection,
+) []SubQueryResult {
+	results := make([]SubQueryResult, len(queries))
+
+	var wg sync.WaitGroup
+	for i, q := range queries {
+		wg.Add(1)
+		go func(i int, q SubQuery) {
+			defer wg.Done()
+
+			aggResult, err := Aggregate(ctx, q.Params, q.Aggregation, itemSoldColl)
+			results[i] = newSubQueryResult(q.Name, aggResult, err)
+		}(i, q)
+	}
+	wg.Wait()
+
+	return results
+}
+
+// newSubQueryResult creates the result of the named sub-query from its
+// aggregation-output, or from the error if it failed.
+func newSubQueryResult(name string, aggResult []interface{}, err error) SubQueryResult {
+	result := SubQueryResult{
+		Name: name,
+	}
+	if err != nil {
+		result.Error = err.Error()
+		return result
+	}
+	result.ReportResult, result.RowErrors = DecodeAggregateRows(aggResult)
+	result.Empty = len(aggResult) == 0
+	return result
+}
@@ -0,0 +1,543 @@
+package report
+
+import (
+	"fmt"
+	"math"
+	"sort"
+	"time"
+)
+
+// Chart types.
+const (
+	// ChartBar is a bar chart of the total sold weight of the top SKUs.
+	ChartBar = "bar"
+	// ChartLine is a line chart of the sold weight of the top SKUs per
+	// time-bucket. Requires a bucketed report.
+	ChartLine = "line"
+)
+
+// Chart image formats.
+const (
+	ChartFormatSVG = "svg"
+	ChartFormatPNG = "png"
+)
+
+// Limits and defaults of ChartOptions.
+const (
+	DefaultChartWidth      = 640
+	DefaultLineChartHeight = 320
+	DefaultChartSeries     = 5
+	MaxChartSeries         = 10
+	MinChartSize           = 200
+	MaxChartSize           = 4096
+)
+
+// Colors and dimensions of charts.
+const (
+	chartBackground = "#ffffff"
+	chartBarColor   = "#4e79a7"
+	chartTextColor  = "#333333"
+	chartAxisColor  = "#999999"
+	chartGridColor  = "#e5e5e5"
+
+	chartWidth      = 640
+	chartRowHeight  = 24
+	chartLabelWidth = 160
+	chartPadding    = 8
+)
+
+// chartPalette are the colors of the series in line charts.
+var chartPalette = []string{
+	"#4e79a7", "#f28e2b", "#e15759", "#76b7b2", "#59a14f",
+	"#edc948", "#b07aa1", "#ff9da7", "#9c755f", "#bab0ac",
+}
+
+// ChartOptions configure the chart rendered from a report.
+type ChartOptions struct {
+	// Type is ChartBar (default) or ChartLine.
+	Type string `json:"type,omitempty"`
+	// Format is ChartFormatSVG (default) or ChartFormatPNG.
+	Format string `json:"format,omitempty"`
+	// Width in pixels. Defaults to DefaultChartWidth.
+	Width int `json:"width,omitempty"`
+	// Height in pixels. Defaults to DefaultLineChartHeight for line charts,
+	// and to fit all bars for bar charts.
+	Height int `json:"height,omitempty"`
+	// Series is the number of SKUs charted, those with the most sold weight.
+	// Defaults to DefaultChartSeries.
+	Series int `json:"series,omitempty"`
+}
+
+// Validate checks the options for unknown types, formats and out-of-range
+// sizes.
+func (o ChartOptions) Validate() error {
+	fields := fieldErrors{}
+	switch o.Type {
+	case "", ChartBar, ChartLine:
+	default:
+		fields.add("type", "unknown chart type: \"%s\"", o.Type)
+	}
+	switch o.Format {
+	case "", ChartFormatSVG, ChartFormatPNG:
+	default:
+		fields.add("format", "unknown chart format: \"%s\"", o.Format)
+	}
+	sizes := []struct {
+		field string
+		value int
+	}{
+		{"width", o.Width},
+		{"height", o.Height},
+	}
+	for _, size := range sizes {
+		if size.value != 0 && (size.value < MinChartSize || size.value > MaxChartSize) {
+			fields.add(
+				size.field, "chart %s must be between %d and %d",
+				size.field, MinChartSize, MaxChartSize,
+			)
+		}
+	}
+	if o.Series < 0 || o.Series > MaxChartSeries {
+		fields.add("series", "chart series must be between 1 and %d", MaxChartSeries)
+	}
+	return fields.err()
+}
+
+// withDefaults returns the options with defaults set for blank values.
+func (o ChartOptions) withDefaults(bars int) ChartOptions {
+	if o.Type == "" {
+		o.Type = ChartBar
+	}
+	if o.Format == "" {
+		o.Format = ChartFormatSVG
+	}
+	if o.Width == 0 {
+		o.Width = DefaultChartWidth
+	}
+	if o.Series == 0 {
+		o.Series = DefaultChartSeries
+	}
+	if o.Height == 0 {
+		if o.Type == ChartLine {
+			o.Height = DefaultLineChartHeight
+		} else {
+			o.Height = horizontalBarsHeight(int(math.Min(float64(bars), float64(o.Series))))
+		}
+	}
+	return o
+}
+
+// ChartContentType is the MIME-type of charts in the format.
+func ChartContentType(format string) string {
+	if format == ChartFormatPNG {
+		return "image/png"
+	}
+	return "image/svg+xml"
+}
+
+// RenderChart renders a chart of the report's rows as SVG or PNG image.
+// The returned chart has its type and format set, so it can be stored
+// using StoreChart.
+func RenderChart(rep *SoldReport, opts ChartOptions) (*ReportChart, error) {
+	err := opts.Validate()
+	if err != nil {
+		return nil, err
+	}
+	skus, _ := rankSKUsBySoldWeight(rep.ReportResult)
+	opts = opts.withDefaults(len(skus))
+
+	var c chartCanvas
+	var svg *svgCanvas
+	var png *pngCanvas
+	title := fmt.Sprintf("Sold weight of top %d SKUs", opts.Series)
+	if opts.Format == ChartFormatPNG {
+		png = newPNGCanvas(opts.Width, opts.Height)
+		c = png
+	} else {
+		svg = newSVGCanvas(opts.Width, opts.Height, title)
+		c = svg
+	}
+
+	switch {
+	case len(rep.ReportResult) == 0:
+		drawEmpty(c)
+	case opts.Type == ChartLine:
+		buckets, series := soldWeightSeries(rep.ReportResult, opts.Series)
+		if len(buckets) == 0 {
+			fields := fieldErrors{}
+			fields.add("type", "line charts require a report with time-buckets")
+			return nil, fields.err()
+		}
+		drawLineChart(c, buckets, series)
+	default:
+		drawHorizontalBars(c, topSKUsBySoldWeight(rep.ReportResult, opts.Series))
+	}
+
+	chart := &ReportChart{
+		Type:      opts.Type,
+		Format:    opts.Format,
+		Timestamp: time.Now().Unix(),
+	}
+	if png != nil {
+		chart.Data, err = png.Bytes()
+		if err != nil {
+			return nil, err
+		}
+	} else {
+		chart.Data = svg.Bytes()
+	}
+	return chart, nil
+}
+
+// textAnchor aligns text relative to its position.
+type textAnchor string
+
+const (
+	anchorStart  textAnchor = "start"
+	anchorMiddle textAnchor = "middle"
+	anchorEnd    textAnchor = "end"
+)
+
+type chartPoint struct {
+	X float64
+	Y float64
+}
+
+// chartCanvas draws the shapes of charts. Positions are in pixels from the
+// top-left corner, and text is positioned by its baseline.
+type chartCanvas interface {
+	Size() (width int, height int)
+	Rect(x, y, w, h float64, color string, title string)
+	Line(x1, y1, x2, y2 float64, color string)
+	Polyline(points []chartPoint, color string)
+	Text(x, y float64, text string, anchor textAnchor, color string)
+}
+
+// chartBar is a labelled value of a bar chart. Display is the formatted
+// value shown next to the bar.
+type chartBar struct {
+	Label   string
+	Value   float64
+	Display string
+}
+
+// chartSeries are the values of a line chart's line, one per time-bucket.
+type chartSeries struct {
+	Label  string
+	Values []float64
+}
+
+// rankSKUsBySoldWeight sums the sold weight per SKU, and returns the SKUs
+// in descending order of sold weight.
+func rankSKUsBySoldWeight(rows []ReportResult) ([]string, map[string]float64) {
+	totals := map[string]float64{}
+	skus := []string{}
+	for _, r := range rows {
+		if _, ok := totals[r.SKU]; !ok {
+			skus = append(skus, r.SKU)
+		}
+		totals[r.SKU] += r.SoldWeight
+	}
+	sort.SliceStable(skus, func(i, j int) bool {
+		return totals[skus[i]] > totals[skus[j]]
+	})
+	return skus, totals
+}
+
+// topSKUsBySoldWeight returns the n SKUs with the most sold weight, in
+// descending order.
+func topSKUsBySoldWeight(rows []ReportResult, n int) []chartBar {
+	skus, totals := rankSKUsBySoldWeight(rows)
+	if len(skus) > n {
+		skus = skus[:n]
+	}
+
+	bars := make([]chartBar, len(skus))
+	for i, sku := range skus {
+		bars[i] = chartBar{
+			Label:   skuLabel(sku),
+			Value:   totals[sku],
+			Display: displayNumber(totals[sku]),
+		}
+	}
+	return bars
+}
+
+// soldWeightSeries returns the sold weight per time-bucket of the n SKUs
+// with the most sold weight. Buckets without rows for a SKU have no sold
+// weight. Rows without bucket are ignored.
+func soldWeightSeries(rows []ReportResult, n int) ([]int64, []chartSeries) {
+	buckets, bucketRows := groupRowsByBucket(rows)
+	if len(buckets) == 0 {
+		return nil, nil
+	}
+	bucketed := []ReportResult{}
+	for _, bucket := range buckets {
+		bucketed = append(bucketed, bucketRows[bucket]...)
+	}
+	skus, _ := rankSKUsBySoldWeight(bucketed)
+	if len(skus) > n {
+		skus = skus[:n]
+	}
+
+	series := make([]chartSeries, len(skus))
+	for i, sku := range skus {
+		series[i] = chartSeries{
+			Label:  skuLabel(sku),
+			Values: make([]float64, len(buckets)),
+		}
+		for j, bucket := range buckets {
+			for _, r := range bucketRows[bucket] {
+				if r.SKU == sku {
+					series[i].Values[j] += r.SoldWeight
+				}
+			}
+		}
+	}
+	return buckets, series
+}
+
+// sellThroughDistribution counts the rows per 10%-range of sell-through.
+// Rows without total weight are not counted, and sell-through above 100%
+// is counted in the last range.
+func sellThroughDistribution(rows []ReportResult) []chartBar {
+	bars := make([]chartBar, 10)
+	for i := range bars {
+		bars[i].Label = fmt.Sprintf("%d-%d%%", i*10, (i+1)*10)
+	}
+	for _, r := range rows {
+		if r.TotalWeight == 0 {
+			continue
+		}
+		bin := int(r.SellThrough() * 10)
+		if bin < 0 {
+			bin = 0
+		}
+		if bin > 9 {
+			bin = 9
+		}
+		bars[bin].Value++
+	}
+	for i := range bars {
+		bars[i].Display = fmt.Sprintf("%.0f", bars[i].Value)
+	}
+	return bars
+}
+
+func skuLabel(sku string) string {
+	if sku == "" {
+		return "(none)"
+	}
+	return sku
+}
+
+// displayNumber formats numbers for charts and HTML reports, grouped and
+// rounded to 2 decimal places.
+func displayNumber(n float64) string {
+	en := localeFormats["en"]
+	return formatNumber(n, &en, 2)
+}
+
+// axisNumber formats numbers of chart-axes, grouped and without trailing
+// zeros.
+func axisNumber(n float64) string {
+	en := localeFormats["en"]
+	return formatNumber(math.Round(n*100)/100, &en, 0)
+}
+
+// maxBarValue is the largest value of the bars, or 1 if there are no
+// positive values, so it can be used for scaling.
+func maxBarValue(bars []chartBar) float64 {
+	max := 0.0
+	for _, b := range bars {
+		if b.Value > max {
+			max = b.Value
+		}
+	}
+	if max == 0 {
+		return 1
+	}
+	return max
+}
+
+// niceCeil rounds the number up to 1, 2 or 5 times a power of 10, so axes
+// have round tick-values.
+func niceCeil(n float64) float64 {
+	if n <= 0 {
+		return 1
+	}
+	magnitude := math.Pow(10, math.Floor(math.Log10(n)))
+	for _, m := range []float64{1, 2, 5} {
+		if m*magnitude >= n {
+			return m * magnitude
+		}
+	}
+	return 10 * magnitude
+}
+
+// truncateLabel shortens labels longer than max runes, so they fit beside
+// their bars. Full labels are kept in the bars' titles.
+func truncateLabel(label string, max int) string {
+	runes := []rune(label)
+	if len(runes) <= max {
+		return label
+	}
+	// Bitmap-fonts of PNG charts don't have an ellipsis-glyph
+	return string(runes[:max-3]) + "..."
+}
+
+// drawEmpty draws the placeholder of charts without data.
+func drawEmpty(c chartCanvas) {
+	c.Text(chartPadding, 24, "No data", anchorStart, chartAxisColor)
+}
+
+// horizontalBarsHeight is the height fitting n horizontal bars.
+func horizontalBarsHeight(n int) int {
+	if n < 1 {
+		n = 1
+	}
+	return n*chartRowHeight + 2*chartPadding
+}
+
+// drawHorizontalBars draws the bars as horizontal bar chart, with the
+// labels left of the bars and the displayed values right of them.
+func drawHorizontalBars(c chartCanvas, bars []chartBar) {
+	if len(bars) == 0 {
+		drawEmpty(c)
+		return
+	}
+	width, height := c.Size()
+	rowHeight := float64(height-2*chartPadding) / float64(len(bars))
+	if rowHeight > chartRowHeight {
+		rowHeight = chartRowHeight
+	}
+	// Leave room for the displayed values
+	maxBarWidth := float64(width - chartLabelWidth - 96)
+	max := maxBarValue(bars)
+
+	for i, b := range bars {
+		y := chartPadding + float64(i)*rowHeight
+		textY := y + rowHeight/2 + 4
+		barWidth := math.Max(b.Value/max*maxBarWidth, 0)
+		c.Text(
+			chartLabelWidth-chartPadding, textY,
+			truncateLabel(b.Label, 20), anchorEnd, chartTextColor,
+		)
+		c.Rect(
+			chartLabelWidth, y+rowHeight/6, barWidth, rowHeight*2/3,
+			chartBarColor, b.Label+": "+b.Display,
+		)
+		c.Text(chartLabelWidth+barWidth+6, textY, b.Display, anchorStart, chartTextColor)
+	}
+	bottom := chartPadding + float64(len(bars))*rowHeight
+	c.Line(chartLabelWidth, chartPadding, chartLabelWidth, bottom, chartAxisColor)
+}
+
+// drawColumns draws the bars as vertical columns, with the labels below the
+// columns and the displayed values above them.
+func drawColumns(c chartCanvas, bars []chartBar) {
+	if len(bars) == 0 {
+		drawEmpty(c)
+		return
+	}
+	width, height := c.Size()
+	// Space for values above, and labels below the columns
+	top := float64(chartPadding + 16)
+	bottom := float64(height - chartPadding - 16)
+	slot := float64(width-2*chartPadding) / float64(len(bars))
+	colWidth := slot * 0.8
+	max := maxBarValue(bars)
+
+	for i, b := range bars {
+		center := chartPadding + slot*float64(i) + slot/2
+		colHeight := math.Max(b.Value/max*(bottom-top), 0)
+		colY := bottom - colHeight
+		c.Rect(
+			center-colWidth/2, colY, colWidth, colHeight,
+			chartBarColor, b.Label+": "+b.Display,
+		)
+		c.Text(center, colY-4, b.Display, anchorMiddle, chartTextColor)
+		c.Text(center, bottom+14, truncateLabel(b.Label, 12), anchorMiddle, chartTextColor)
+	}
+	c.Line(chartPadding, bottom, float64(width-chartPadding), bottom, chartAxisColor)
+}
+
+// drawLineChart draws a line per series over the time-buckets, with a
+// legend right of the plot.
+func drawLineChart(c chartCanvas, buckets []int64, series []chartSeries) {
+	width, height := c.Size()
+	legendWidth := 140.0
+	left := 64.0
+	right := float64(width) - legendWidth - chartPadding
+	top := float64(chartPadding + 8)
+	bottom := float64(height - 32)
+
+	max := 0.0
+	for _, s := range series {
+		for _, v := range s.Values {
+			max = math.Max(max, v)
+		}
+	}
+	max = niceCeil(max)
+	yPos := func(v float64) float64 {
+		return bottom - v/max*(bottom-top)
+	}
+	xPos := func(i int) float64 {
+		if len(buckets) == 1 {
+			return (left + right) / 2
+		}
+		return left + float64(i)*(right-left)/float64(len(buckets)-1)
+	}
+
+	// Grid and y-axis labels
+	ticks := 4
+	for i := 0; i <= ticks; i++ {
+		v := max * float64(i) / float64(ticks)
+		y := yPos(v)
+		color := chartGridColor
+		if i == 0 {
+			color = chartAxisColor
+		}
+		c.Line(left, y, right, y, color)
+		c.Text(left-6, y+4, axisNumber(v), anchorEnd, chartTextColor)
+	}
+
+	// X-axis labels, at most 6 so they don't overlap
+	labelStep := int(math.Ceil(float64(len(buckets)) / 6))
+	for i, bucket := range buckets {
+		if i%labelStep == 0 {
+			c.Text(xPos(i), bottom+16, bucketLabel(bucket, buckets), anchorMiddle, chartTextColor)
+		}
+	}
+
+	for i, s := range series {
+		color := chartPalette[i%len(chartPalette)]
+		points := make([]chartPoint, len(s.Values))
+		for j, v := range s.Values {
+			points[j] = chartPoint{X: xPos(j), Y: yPos(v)}
+			c.Rect(
+				points[j].X-2, points[j].Y-2, 4, 4,
+				color, s.Label+": "+displayNumber(v),
+			)
+		}
+		if len(points) > 1 {
+			c.Polyline(points, color)
+		}
+
+		legendX := right + 16
+		legendY := top + float64(i)*18
+		c.Rect(legendX, legendY, 10, 10, color, "")
+		c.Text(legendX+14, legendY+10, truncateLabel(s.Label, 16), anchorStart, chartTextColor)
+	}
+}
+
+// bucketLabel formats the time-bucket for chart-axes. Hours are only shown
+// if any of the buckets is not at midnight.
+func bucketLabel(bucket int64, buckets []int64) string {
+	for _, b := range buckets {
+		if time.Unix(b, 0).UTC().Hour() != 0 {
+			return time.Unix(bucket, 0).UTC().Format("01-02 15h")
+		}
+	}
+	return time.Unix(bucket, 0).UTC().Format("2006-01-02")
+}
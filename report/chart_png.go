@@ -0,0 +1,115 @@
+package report
+
+import (
+	"bytes"
+	"image"
+	"image/color"
+	"image/draw"
+	"image/png"
+	"math"
+	"strconv"
+
+	"github.com/pkg/errors"
+	"golang.org/x/image/font"
+	"golang.org/x/image/font/basicfont"
+	"golang.org/x/image/math/fixed"
+)
+
+// pngCanvas draws charts on an RGBA image. Text uses a fixed-size bitmap
+// font, so no font-files are required.
+type pngCanvas struct {
+	img *image.RGBA
+}
+
+func newPNGCanvas(width int, height int) *pngCanvas {
+	img := image.NewRGBA(image.Rect(0, 0, width, height))
+	draw.Draw(img, img.Bounds(), image.NewUniform(parseHexColor(chartBackground)), image.ZP, draw.Src)
+	return &pngCanvas{img: img}
+}
+
+func (c *pngCanvas) Size() (int, int) {
+	size := c.img.Bounds().Size()
+	return size.X, size.Y
+}
+
+// Rect fills the rectangle. PNGs cannot show titles, so title is ignored.
+func (c *pngCanvas) Rect(x, y, w, h float64, hexColor string, title string) {
+	rect := image.Rect(
+		int(math.Round(x)), int(math.Round(y)),
+		int(math.Round(x+w)), int(math.Round(y+h)),
+	)
+	draw.Draw(c.img, rect, image.NewUniform(parseHexColor(hexColor)), image.ZP, draw.Src)
+}
+
+func (c *pngCanvas) Line(x1, y1, x2, y2 float64, hexColor string) {
+	c.line(x1, y1, x2, y2, parseHexColor(hexColor), 1)
+}
+
+func (c *pngCanvas) Polyline(points []chartPoint, hexColor string) {
+	col := parseHexColor(hexColor)
+	for i := 1; i < len(points); i++ {
+		c.line(points[i-1].X, points[i-1].Y, points[i].X, points[i].Y, col, 2)
+	}
+}
+
+// line draws a line of the thickness, by plotting squares along it.
+func (c *pngCanvas) line(x1, y1, x2, y2 float64, col color.RGBA, thickness int) {
+	steps := math.Max(math.Abs(x2-x1), math.Abs(y2-y1))
+	if steps < 1 {
+		steps = 1
+	}
+	for i := 0.0; i <= steps; i++ {
+		x := int(math.Round(x1 + (x2-x1)*i/steps))
+		y := int(math.Round(y1 + (y2-y1)*i/steps))
+		for dx := 0; dx < thickness; dx++ {
+			for dy := 0; dy < thickness; dy++ {
+				c.img.SetRGBA(x+dx, y+dy, col)
+			}
+		}
+	}
+}
+
+func (c *pngCanvas) Text(x, y float64, text string, anchor textAnchor, hexColor string) {
+	d := &font.Drawer{
+		Dst:  c.img,
+		Src:  image.NewUniform(parseHexColor(hexColor)),
+		Face: basicfont.Face7x13,
+	}
+	width := float64(d.MeasureString(text).Ceil())
+	switch anchor {
+	case anchorMiddle:
+		x -= width / 2
+	case anchorEnd:
+		x -= width
+	}
+	d.Dot = fixed.P(int(math.Round(x)), int(math.Round(y)))
+	d.DrawString(text)
+}
+
+// Bytes encodes the image as PNG.
+func (c *pngCanvas) Bytes() ([]byte, error) {
+	buf := &bytes.Buffer{}
+	err := png.Encode(buf, c.img)
+	if err != nil {
+		return nil, errors.Wrap(err, "Error encoding PNG")
+	}
+	return buf.Bytes(), nil
+}
+
+// parseHexColor parses colors in "#rrggbb" format. Invalid colors are
+// parsed as black.
+func parseHexColor(hex string) color.RGBA {
+	if len(hex) != 7 || hex[0] != '#' {
+		return color.RGBA{A: 255}
+	}
+	rgb, err := strconv.ParseUint(hex[1:], 16, 32)
+	if err != nil {
+		return color.RGBA{A: 255}
+	}
+	return color.RGBA{
+		R: uint8(rgb >> 16),
+		G: uint8(rgb >> 8),
+		B: uint8(rgb),
+		A: 255,
+	}
+}
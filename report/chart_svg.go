@@ -0,0 +1,111 @@
+package report
+
+import (
+	"bytes"
+	"fmt"
+	"html"
+)
+
+// svgFont is the font of text in SVG charts. PNG charts use a fixed-size
+// font of similar size.
+const svgFont = `font-family="Helvetica, Arial, sans-serif" font-size="12"`
+
+// svgCanvas draws charts as SVG elements.
+type svgCanvas struct {
+	buf    *bytes.Buffer
+	width  int
+	height int
+}
+
+func newSVGCanvas(width int, height int, title string) *svgCanvas {
+	c := &svgCanvas{
+		buf:    &bytes.Buffer{},
+		width:  width,
+		height: height,
+	}
+	fmt.Fprintf(
+		c.buf,
+		`<svg xmlns="http://www.w3.org/2000/svg" width="%d" height="%d" viewBox="0 0 %d %d" role="img" aria-label="%s">`,
+		width, height, width, height, html.EscapeString(title),
+	)
+	fmt.Fprintf(c.buf, `<rect width="%d" height="%d" fill="%s"/>`, width, height, chartBackground)
+	return c
+}
+
+func (c *svgCanvas) Size() (int, int) {
+	return c.width, c.height
+}
+
+func (c *svgCanvas) Rect(x, y, w, h float64, color string, title string) {
+	fmt.Fprintf(
+		c.buf,
+		`<rect x="%.1f" y="%.1f" width="%.1f" height="%.1f" fill="%s">`,
+		x, y, w, h, color,
+	)
+	if title != "" {
+		fmt.Fprintf(c.buf, `<title>%s</title>`, html.EscapeString(title))
+	}
+	c.buf.WriteString(`</rect>`)
+}
+
+func (c *svgCanvas) Line(x1, y1, x2, y2 float64, color string) {
+	fmt.Fprintf(
+		c.buf,
+		`<line x1="%.1f" y1="%.1f" x2="%.1f" y2="%.1f" stroke="%s"/>`,
+		x1, y1, x2, y2, color,
+	)
+}
+
+func (c *svgCanvas) Polyline(points []chartPoint, color string) {
+	c.buf.WriteString(`<polyline fill="none" stroke-width="2" points="`)
+	for i, p := range points {
+		if i > 0 {
+			c.buf.WriteString(" ")
+		}
+		fmt.Fprintf(c.buf, "%.1f,%.1f", p.X, p.Y)
+	}
+	fmt.Fprintf(c.buf, `" stroke="%s"/>`, color)
+}
+
+func (c *svgCanvas) Text(x, y float64, text string, anchor textAnchor, color string) {
+	fmt.Fprintf(
+		c.buf,
+		`<text x="%.1f" y="%.1f" text-anchor="%s" fill="%s" %s>%s</text>`,
+		x, y, anchor, color, svgFont, html.EscapeString(text),
+	)
+}
+
+// Bytes closes the svg-element, and returns the SVG document.
+func (c *svgCanvas) Bytes() []byte {
+	c.buf.WriteString("</svg>")
+	return c.buf.Bytes()
+}
+
+// horizontalBarChartSVG renders the bars as horizontal bar chart, with the
+// labels left of the bars and the displayed values right of them.
+func horizontalBarChartSVG(title string, bars []chartBar) string {
+	if len(bars) == 0 {
+		return emptyChartSVG(title)
+	}
+	c := newSVGCanvas(chartWidth, horizontalBarsHeight(len(bars)), title)
+	drawHorizontalBars(c, bars)
+	return string(c.Bytes())
+}
+
+// columnChartSVG renders the bars as vertical columns, with the labels
+// below the columns and the displayed values above them.
+func columnChartSVG(title string, bars []chartBar, height int) string {
+	if len(bars) == 0 {
+		return emptyChartSVG(title)
+	}
+	c := newSVGCanvas(chartWidth, height, title)
+	drawColumns(c, bars)
+	return string(c.Bytes())
+}
+
+// emptyChartSVG is shown in place of charts without data.
+func emptyChartSVG(title string) string {
+	c := newSVGCanvas(chartWidth, 40, title)
+	drawEmpty(c)
+	return string(c.Bytes())
+}
@@ -0,0 +1,95 @@
+package report
+
+import (
+	"bytes"
+	"encoding/xml"
+	"image/png"
+	"io"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Chart rendering", func() {
+	var rep *SoldReport
+
+	BeforeEach(func() {
+		rep = &SoldReport{
+			ReportResult: []ReportResult{
+				ReportResult{
+					SKU:         "test-sku1",
+					Bucket:      1539302400,
+					SoldWeight:  10,
+					TotalWeight: 40,
+				},
+				ReportResult{
+					SKU:         "test-sku2",
+					Bucket:      1539302400,
+					SoldWeight:  50,
+					TotalWeight: 60,
+				},
+				ReportResult{
+					SKU:         "test-sku1",
+					Bucket:      1539388800,
+					SoldWeight:  30,
+					TotalWeight: 40,
+				},
+			},
+		}
+	})
+
+	It("renders bar charts as SVG by default", func() {
+		chart, err := RenderChart(rep, ChartOptions{})
+		Expect(err).ToNot(HaveOccurred())
+		Expect(chart.Type).To(Equal(ChartBar))
+		Expect(chart.Format).To(Equal(ChartFormatSVG))
+		// Is well-formed XML
+		decoder := xml.NewDecoder(bytes.NewReader(chart.Data))
+		for {
+			_, err = decoder.Token()
+			if err == io.EOF {
+				break
+			}
+			Expect(err).ToNot(HaveOccurred())
+		}
+		Expect(string(chart.Data)).To(ContainSubstring("test-sku2: 50.00"))
+	})
+
+	It("renders line charts as PNG of the requested size", func() {
+		chart, err := RenderChart(rep, ChartOptions{
+			Type:   ChartLine,
+			Format: ChartFormatPNG,
+			Width:  800,
+			Height: 400,
+		})
+		Expect(err).ToNot(HaveOccurred())
+
+		img, err := png.Decode(bytes.NewReader(chart.Data))
+		Expect(err).ToNot(HaveOccurred())
+		Expect(img.Bounds().Dx()).To(Equal(800))
+		Expect(img.Bounds().Dy()).To(Equal(400))
+	})
+
+	It("charts the sold weight of the top SKUs per bucket", func() {
+		buckets, series := soldWeightSeries(rep.ReportResult, 1)
+		Expect(buckets).To(Equal([]int64{1539302400, 1539388800}))
+		Expect(series).To(HaveLen(1))
+		Expect(series[0].Label).To(Equal("test-sku2"))
+		Expect(series[0].Values).To(Equal([]float64{50, 0}))
+	})
+
+	It("requires buckets for line charts", func() {
+		for i := range rep.ReportResult {
+			rep.ReportResult[i].Bucket = 0
+		}
+		_, err := RenderChart(rep, ChartOptions{Type: ChartLine})
+		Expect(err).To(HaveOccurred())
+	})
+
+	It("rejects invalid options", func() {
+		Expect(ChartOptions{Type: "pie"}.Validate()).ToNot(Succeed())
+		Expect(ChartOptions{Format: "gif"}.Validate()).ToNot(Succeed())
+		Expect(ChartOptions{Width: 10}.Validate()).ToNot(Succeed())
+		Expect(ChartOptions{Series: MaxChartSeries + 1}.Validate()).ToNot(Succeed())
+	})
+})
@@ -0,0 +1,126 @@
+package report
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+
+	"github.com/pkg/errors"
+)
+
+// ResultChunk is a part of a response-result that was too large for a
+// single Kafka-message. All chunks of a result are sent with the same
+// CorrelationID, and can be reassembled using a ChunkAssembler.
+type ResultChunk struct {
+	// Sequence is the 0-based position of this chunk.
+	Sequence int `json:"chunkSequence"`
+	// Total is the number of chunks the result was split into.
+	Total int `json:"chunkTotal"`
+	// Checksum is the hex-encoded SHA-256 of the complete result.
+	Checksum string `json:"chunkChecksum"`
+	Data     []byte `json:"chunkData"`
+}
+
+// SplitResult splits the result into chunks, each containing at most
+// maxChunkSize bytes of data.
+func SplitResult(result []byte, maxChunkSize int) ([]ResultChunk, error) {
+	if maxChunkSize <= 0 {
+		return nil, errors.New("maxChunkSize must be greater than 0")
+	}
+
+	sum := sha256.Sum256(result)
+	checksum := hex.EncodeToString(sum[:])
+
+	total := (len(result) + maxChunkSize - 1) / maxChunkSize
+	if total == 0 {
+		total = 1
+	}
+	chunks := make([]ResultChunk, 0, total)
+	for i := 0; i < total; i++ {
+		start := i * maxChunkSize
+		end := start + maxChunkSize
+		if end > len(result) {
+			end = len(result)
+		}
+		chunks = append(chunks, ResultChunk{
+			Sequence: i,
+			Total:    total,
+			Checksum: checksum,
+			Data:     result[start:end],
+		})
+	}
+	return chunks, nil
+}
+
+// ParseChunk returns the ResultChunk contained in the response-result.
+// The bool is false if the result is not a chunk.
+func ParseChunk(result []byte) (*ResultChunk, bool) {
+	chunk := &ResultChunk{}
+	err := json.Unmarshal(result, chunk)
+	if err != nil || chunk.Total < 1 || chunk.Checksum == "" {
+		return nil, false
+	}
+	return chunk, true
+}
+
+// ChunkAssembler reassembles the chunks of a single result.
+// Chunks can be added in any order, and duplicate chunks are ignored.
+type ChunkAssembler struct {
+	chunks   [][]byte
+	received int
+	checksum string
+}
+
+// Add adds the chunk to the assembler, and returns true once all chunks
+// have been received.
+func (a *ChunkAssembler) Add(chunk ResultChunk) (bool, error) {
+	if a.chunks == nil {
+		if chunk.Total < 1 {
+			return false, errors.New("chunk-total must be greater than 0")
+		}
+		a.chunks = make([][]byte, chunk.Total)
+		a.checksum = chunk.Checksum
+	}
+
+	if chunk.Total != len(a.chunks) || chunk.Checksum != a.checksum {
+		return false, errors.New("chunk does not belong to this result")
+	}
+	if chunk.Sequence < 0 || chunk.Sequence >= len(a.chunks) {
+		return false, errors.Errorf("chunk-sequence %d out of range", chunk.Sequence)
+	}
+
+	if a.chunks[chunk.Sequence] == nil {
+		data := chunk.Data
+		if data == nil {
+			data = []byte{}
+		}
+		a.chunks[chunk.Sequence] = data
+		a.received++
+	}
+	return a.Complete(), nil
+}
+
+// Complete returns true if all chunks have been received.
+func (a *ChunkAssembler) Complete() bool {
+	return a.chunks != nil && a.received == len(a.chunks)
+}
+
+// Result returns the reassembled result, after verifying its checksum.
+func (a *ChunkAssembler) Result() ([]byte, error) {
+	if !a.Complete() {
+		return nil, errors.Errorf(
+			"received %d of %d chunks", a.received, len(a.chunks),
+		)
+	}
+
+	result := []byte{}
+	for _, data := range a.chunks {
+		result = append(result, data...)
+	}
+
+	sum := sha256.Sum256(result)
+	if hex.EncodeToString(sum[:]) != a.checksum {
+		return nil, errors.New("checksum mismatch in reassembled result")
+	}
+	return result, nil
+}
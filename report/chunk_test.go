@@ -0,0 +1,85 @@
+package report
+
+import (
+	"bytes"
+	"encoding/json"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Result chunking", func() {
+	var result []byte
+
+	BeforeEach(func() {
+		result = bytes.Repeat([]byte(`{"sku":"test-sku1","soldWeight":101}`), 100)
+	})
+
+	It("splits result into chunks of max size", func() {
+		chunks, err := SplitResult(result, 1000)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(chunks).To(HaveLen(4))
+		for i, chunk := range chunks {
+			Expect(chunk.Sequence).To(Equal(i))
+			Expect(chunk.Total).To(Equal(4))
+			Expect(len(chunk.Data)).To(BeNumerically("<=", 1000))
+		}
+	})
+
+	It("reassembles chunks received out of order and duplicated", func() {
+		chunks, err := SplitResult(result, 1000)
+		Expect(err).ToNot(HaveOccurred())
+
+		assembler := &ChunkAssembler{}
+		for _, i := range []int{2, 0, 2, 3} {
+			complete, err := assembler.Add(chunks[i])
+			Expect(err).ToNot(HaveOccurred())
+			Expect(complete).To(BeFalse())
+		}
+		complete, err := assembler.Add(chunks[1])
+		Expect(err).ToNot(HaveOccurred())
+		Expect(complete).To(BeTrue())
+
+		reassembled, err := assembler.Result()
+		Expect(err).ToNot(HaveOccurred())
+		Expect(reassembled).To(Equal(result))
+	})
+
+	It("returns error for chunks of a different result", func() {
+		chunks, err := SplitResult(result, 1000)
+		Expect(err).ToNot(HaveOccurred())
+		otherChunks, err := SplitResult(result[1:], 1000)
+		Expect(err).ToNot(HaveOccurred())
+
+		assembler := &ChunkAssembler{}
+		_, err = assembler.Add(chunks[0])
+		Expect(err).ToNot(HaveOccurred())
+		_, err = assembler.Add(otherChunks[1])
+		Expect(err).To(HaveOccurred())
+	})
+
+	It("returns error when result is incomplete", func() {
+		chunks, err := SplitResult(result, 1000)
+		Expect(err).ToNot(HaveOccurred())
+
+		assembler := &ChunkAssembler{}
+		_, err = assembler.Add(chunks[0])
+		Expect(err).ToNot(HaveOccurred())
+		_, err = assembler.Result()
+		Expect(err).To(HaveOccurred())
+	})
+
+	It("parses chunks but not regular results", func() {
+		chunks, err := SplitResult(result, 1000)
+		Expect(err).ToNot(HaveOccurred())
+		chunkResult, err := json.Marshal(chunks[0])
+		Expect(err).ToNot(HaveOccurred())
+
+		_, isChunk := ParseChunk(chunkResult)
+		Expect(isChunk).To(BeTrue())
+		_, isChunk = ParseChunk([]byte(`{"reportResult":[]}`))
+		Expect(isChunk).To(BeFalse())
+		_, isChunk = ParseChunk([]byte(`[]`))
+		Expect(isChunk).To(BeFalse())
+	})
+})
@@ -0,0 +1,26 @@
+package report
+
+import "context"
+
+// runWithContext runs fn, and returns the ctx-error if ctx is done before fn
+// returns. The Mongo-operations of go-mongoutils don't accept a context, and
+// are only bounded by the connection's resource-timeout, so an abandoned
+// operation still runs in background until it completes or times out.
+func runWithContext(ctx context.Context, fn func() error) error {
+	err := ctx.Err()
+	if err != nil {
+		return err
+	}
+
+	done := make(chan error, 1)
+	go func() {
+		done <- fn()
+	}()
+
+	select {
+	case err = <-done:
+		return err
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
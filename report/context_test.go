@@ -0,0 +1,45 @@
+package report
+
+import (
+	"context"
+	"time"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	"github.com/pkg/errors"
+)
+
+var _ = Describe("Context", func() {
+	It("returns the result of the function", func() {
+		err := runWithContext(context.Background(), func() error {
+			return errors.New("test-error")
+		})
+		Expect(err).To(MatchError("test-error"))
+	})
+
+	It("returns the ctx-error if the deadline expires first", func() {
+		ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+		defer cancel()
+
+		release := make(chan struct{})
+		defer close(release)
+		err := runWithContext(ctx, func() error {
+			<-release
+			return nil
+		})
+		Expect(err).To(Equal(context.DeadlineExceeded))
+	})
+
+	It("doesn't run the function if ctx is already done", func() {
+		ctx, cancel := context.WithCancel(context.Background())
+		cancel()
+
+		called := false
+		err := runWithContext(ctx, func() error {
+			called = true
+			return nil
+		})
+		Expect(err).To(Equal(context.Canceled))
+		Expect(called).To(BeFalse())
+	})
+})
@@ -0,0 +1,250 @@
+package report
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/TerrexTech/uuuid"
+	"github.com/pkg/errors"
+)
+
+// Columns of exported report-rows.
+const (
+	ColumnSKU         = "sku"
+	ColumnName        = "name"
+	ColumnLot         = "lot"
+	ColumnBucket      = "bucket"
+	ColumnSoldWeight  = "soldWeight"
+	ColumnTotalWeight = "totalWeight"
+	ColumnSellThrough = "sellThrough"
+)
+
+// DefaultCSVColumns are the columns exported when none are specified.
+var DefaultCSVColumns = []string{
+	ColumnSKU,
+	ColumnName,
+	ColumnLot,
+	ColumnBucket,
+	ColumnSoldWeight,
+	ColumnTotalWeight,
+}
+
+// bucketTimeFormat is the format of time-buckets in exports. Spreadsheets
+// parse this format as date-time.
+const bucketTimeFormat = "2006-01-02 15:04:05"
+
+// numberFormat are the separators used for formatting numbers in a locale.
+type numberFormat struct {
+	decimal string
+	group   string
+}
+
+// localeFormats maps lowercased locales to their number-formats. Locales
+// are matched by full tag first, then by language.
+var localeFormats = map[string]numberFormat{
+	"en":    {decimal: ".", group: ","},
+	"de":    {decimal: ",", group: "."},
+	"de-ch": {decimal: ".", group: "'"},
+	"es":    {decimal: ",", group: "."},
+	"fr":    {decimal: ",", group: " "},
+	"it":    {decimal: ",", group: "."},
+	"nl":    {decimal: ",", group: "."},
+	"pt":    {decimal: ",", group: "."},
+}
+
+// CSVOptions configure the CSV export of reports.
+type CSVOptions struct {
+	// Columns are exported in this order. Defaults to DefaultCSVColumns.
+	Columns []string `json:"columns,omitempty"`
+	// Locale formats numbers with the locale's decimal and grouping
+	// separators, such as "de-DE". Locales using a decimal-comma use ";"
+	// as field-delimiter. If blank, numbers are formatted without grouping,
+	// using "." as decimal separator.
+	Locale string `json:"locale,omitempty"`
+	// Decimals rounds numbers to this many decimal places, if greater
+	// than 0.
+	Decimals int `json:"decimals,omitempty"`
+	// Comments adds the reportID and search-params as "#"-prefixed lines
+	// before the header-row.
+	Comments bool `json:"comments,omitempty"`
+}
+
+// Validate checks the options for unknown columns and locales.
+func (o CSVOptions) Validate() error {
+	fields := fieldErrors{}
+	for i, col := range o.Columns {
+		switch col {
+		case ColumnSKU, ColumnName, ColumnLot, ColumnBucket,
+			ColumnSoldWeight, ColumnTotalWeight, ColumnSellThrough:
+		default:
+			fields.add(fmt.Sprintf("columns[%d]", i), "unknown CSV column: \"%s\"", col)
+		}
+	}
+	if o.Locale != "" {
+		if _, ok := lookupLocale(o.Locale); !ok {
+			fields.add("locale", "unsupported locale: \"%s\"", o.Locale)
+		}
+	}
+	if o.Decimals < 0 {
+		fields.add("decimals", "decimals cannot be negative")
+	}
+	return fields.err()
+}
+
+func lookupLocale(locale string) (numberFormat, bool) {
+	tag := strings.ToLower(strings.Replace(locale, "_", "-", -1))
+	if format, ok := localeFormats[tag]; ok {
+		return format, true
+	}
+	lang := strings.SplitN(tag, "-", 2)[0]
+	format, ok := localeFormats[lang]
+	return format, ok
+}
+
+// SellThrough is the ratio of sold weight to total weight, or 0 if the
+// total weight is 0.
+func (r ReportResult) SellThrough() float64 {
+	if r.TotalWeight == 0 {
+		return 0
+	}
+	return r.SoldWeight / r.TotalWeight
+}
+
+// WriteCSV writes the report's rows as CSV, with a header-row.
+func WriteCSV(w io.Writer, rep *SoldReport, opts CSVOptions) error {
+	err := opts.Validate()
+	if err != nil {
+		return err
+	}
+	columns := opts.Columns
+	if len(columns) == 0 {
+		columns = DefaultCSVColumns
+	}
+
+	var numFormat *numberFormat
+	cw := csv.NewWriter(w)
+	if opts.Locale != "" {
+		format, _ := lookupLocale(opts.Locale)
+		numFormat = &format
+		if format.decimal == "," {
+			cw.Comma = ';'
+		}
+	}
+
+	if opts.Comments {
+		err = writeCSVComments(w, rep)
+		if err != nil {
+			return err
+		}
+	}
+
+	err = cw.Write(columns)
+	if err != nil {
+		return errors.Wrap(err, "Error writing CSV header")
+	}
+	for _, row := range rep.ReportResult {
+		record := make([]string, len(columns))
+		for i, col := range columns {
+			record[i] = csvValue(row, col, numFormat, opts.Decimals)
+		}
+		err = cw.Write(record)
+		if err != nil {
+			return errors.Wrap(err, "Error writing CSV row")
+		}
+	}
+	cw.Flush()
+	err = cw.Error()
+	if err != nil {
+		return errors.Wrap(err, "Error writing CSV")
+	}
+	return nil
+}
+
+func writeCSVComments(w io.Writer, rep *SoldReport) error {
+	searchQuery, err := json.Marshal(rep.SearchQuery)
+	if err != nil {
+		return errors.Wrap(err, "Error marshalling search-params")
+	}
+
+	comments := []string{}
+	if rep.ReportID != (uuuid.UUID{}) {
+		comments = append(comments, "reportID: "+rep.ReportID.String())
+	}
+	if rep.Timestamp != 0 {
+		generated := time.Unix(rep.Timestamp, 0).UTC().Format(bucketTimeFormat)
+		comments = append(comments, "generated: "+generated+" UTC")
+	}
+	comments = append(comments, "searchQuery: "+string(searchQuery))
+
+	for _, comment := range comments {
+		_, err = fmt.Fprintf(w, "# %s\n", comment)
+		if err != nil {
+			return errors.Wrap(err, "Error writing CSV comments")
+		}
+	}
+	return nil
+}
+
+func csvValue(row ReportResult, col string, numFormat *numberFormat, decimals int) string {
+	switch col {
+	case ColumnSKU:
+		return row.SKU
+	case ColumnName:
+		return row.Name
+	case ColumnLot:
+		return row.Lot
+	case ColumnBucket:
+		if row.Bucket == 0 {
+			return ""
+		}
+		return time.Unix(row.Bucket, 0).UTC().Format(bucketTimeFormat)
+	case ColumnSoldWeight:
+		return formatNumber(row.SoldWeight, numFormat, decimals)
+	case ColumnTotalWeight:
+		return formatNumber(row.TotalWeight, numFormat, decimals)
+	case ColumnSellThrough:
+		return formatNumber(row.SellThrough(), numFormat, decimals)
+	default:
+		return ""
+	}
+}
+
+// formatNumber formats the number using the number-format's separators.
+// Numbers are formatted in their shortest representation if decimals is 0.
+func formatNumber(n float64, numFormat *numberFormat, decimals int) string {
+	precision := -1
+	if decimals > 0 {
+		precision = decimals
+	}
+	s := strconv.FormatFloat(n, 'f', precision, 64)
+	if numFormat == nil {
+		return s
+	}
+
+	sign := ""
+	if strings.HasPrefix(s, "-") {
+		sign = "-"
+		s = s[1:]
+	}
+	intPart, fracPart := s, ""
+	if i := strings.Index(s, "."); i >= 0 {
+		intPart, fracPart = s[:i], s[i+1:]
+	}
+
+	grouped := ""
+	for len(intPart) > 3 {
+		grouped = numFormat.group + intPart[len(intPart)-3:] + grouped
+		intPart = intPart[:len(intPart)-3]
+	}
+	grouped = intPart + grouped
+
+	if fracPart == "" {
+		return sign + grouped
+	}
+	return sign + grouped + numFormat.decimal + fracPart
+}
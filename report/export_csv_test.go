@@ -0,0 +1,102 @@
+package report
+
+import (
+	"bytes"
+	"strings"
+
+	"github.com/TerrexTech/uuuid"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("CSV export", func() {
+	var rep *SoldReport
+
+	BeforeEach(func() {
+		reportID, err := uuuid.NewV4()
+		Expect(err).ToNot(HaveOccurred())
+		rep = &SoldReport{
+			ReportID: reportID,
+			SearchQuery: SoldItemParams{
+				Timestamp: &Comparator{
+					Gt: 1539302400,
+					Lt: 1539388800,
+				},
+			},
+			ReportResult: []ReportResult{
+				ReportResult{
+					SKU:         "test-sku1",
+					Name:        "Apple, Red",
+					Bucket:      1539302400,
+					SoldWeight:  1234.5,
+					TotalWeight: 2469,
+				},
+			},
+			Timestamp: 1539400000,
+		}
+	})
+
+	It("writes header and default columns", func() {
+		buf := &bytes.Buffer{}
+		err := WriteCSV(buf, rep, CSVOptions{})
+		Expect(err).ToNot(HaveOccurred())
+		Expect(buf.String()).To(Equal(
+			"sku,name,lot,bucket,soldWeight,totalWeight\n" +
+				"test-sku1,\"Apple, Red\",,2018-10-12 00:00:00,1234.5,2469\n",
+		))
+	})
+
+	It("writes the configured columns in order", func() {
+		buf := &bytes.Buffer{}
+		err := WriteCSV(buf, rep, CSVOptions{
+			Columns:  []string{ColumnSellThrough, ColumnSKU},
+			Decimals: 2,
+		})
+		Expect(err).ToNot(HaveOccurred())
+		Expect(buf.String()).To(Equal("sellThrough,sku\n0.50,test-sku1\n"))
+	})
+
+	It("formats numbers for the locale", func() {
+		buf := &bytes.Buffer{}
+		err := WriteCSV(buf, rep, CSVOptions{
+			Columns: []string{ColumnSKU, ColumnSoldWeight},
+			Locale:  "de-DE",
+		})
+		Expect(err).ToNot(HaveOccurred())
+		Expect(buf.String()).To(Equal("sku;soldWeight\ntest-sku1;1.234,5\n"))
+
+		buf.Reset()
+		err = WriteCSV(buf, rep, CSVOptions{
+			Columns: []string{ColumnSoldWeight},
+			Locale:  "en_US",
+		})
+		Expect(err).ToNot(HaveOccurred())
+		Expect(buf.String()).To(Equal("soldWeight\n\"1,234.5\"\n"))
+	})
+
+	It("writes params and reportID as comment lines", func() {
+		buf := &bytes.Buffer{}
+		err := WriteCSV(buf, rep, CSVOptions{
+			Comments: true,
+		})
+		Expect(err).ToNot(HaveOccurred())
+		lines := strings.Split(buf.String(), "\n")
+		Expect(lines[0]).To(Equal("# reportID: " + rep.ReportID.String()))
+		Expect(lines[1]).To(Equal("# generated: 2018-10-13 03:06:40 UTC"))
+		Expect(lines[2]).To(HavePrefix("# searchQuery: {\"timestamp\":"))
+		Expect(lines[3]).To(HavePrefix("sku,"))
+	})
+
+	It("rejects unknown columns and locales", func() {
+		buf := &bytes.Buffer{}
+		err := WriteCSV(buf, rep, CSVOptions{
+			Columns: []string{"price"},
+		})
+		Expect(err).To(HaveOccurred())
+		err = WriteCSV(buf, rep, CSVOptions{
+			Locale: "xx",
+		})
+		Expect(err).To(HaveOccurred())
+		Expect(buf.Len()).To(BeZero())
+	})
+})
@@ -0,0 +1,174 @@
+package report
+
+import (
+	"fmt"
+	"html/template"
+	"io"
+	"time"
+
+	"github.com/TerrexTech/uuuid"
+	"github.com/pkg/errors"
+)
+
+// HTMLTopSKUs is the number of SKUs shown in the top-SKUs chart of HTML
+// reports.
+const HTMLTopSKUs = 10
+
+// htmlTemplate renders a self-contained HTML report. Styles are embedded and
+// charts are inline SVG, so the file can be emailed or archived as-is.
+var htmlTemplate = template.Must(template.New("report").Parse(`<!DOCTYPE html>
+<html lang="en">
+<head>
+<meta charset="utf-8">
+<title>{{.Title}}</title>
+<style>
+body { font-family: Helvetica, Arial, sans-serif; color: #333; margin: 2em; }
+h1 { font-size: 1.5em; }
+h2 { font-size: 1.2em; margin-top: 2em; }
+table { border-collapse: collapse; }
+th, td { padding: 4px 10px; border-bottom: 1px solid #ddd; text-align: left; }
+th { background: #f5f5f5; }
+td.num { text-align: right; font-variant-numeric: tabular-nums; }
+table.summary th { background: none; }
+.empty { color: #999; }
+</style>
+</head>
+<body>
+<h1>{{.Title}}</h1>
+<table class="summary">
+{{range .Summary}}<tr><th>{{.Label}}</th><td>{{.Value}}</td></tr>
+{{end}}</table>
+
+<h2>Top SKUs by Sold Weight</h2>
+{{.TopSKUsChart}}
+
+<h2>Sell-Through Distribution</h2>
+{{.SellThroughChart}}
+
+<h2>Rows</h2>
+{{if .Rows}}<table>
+<tr><th>SKU</th><th>Name</th><th>Lot</th><th>Bucket</th><th>Sold Weight</th><th>Total Weight</th><th>Sell-Through</th></tr>
+{{range .Rows}}<tr><td>{{.SKU}}</td><td>{{.Name}}</td><td>{{.Lot}}</td><td>{{.Bucket}}</td><td class="num">{{.SoldWeight}}</td><td class="num">{{.TotalWeight}}</td><td class="num">{{.SellThrough}}</td></tr>
+{{end}}</table>
+{{else}}<p class="empty">No rows</p>
+{{end}}</body>
+</html>
+`))
+
+type htmlSummaryItem struct {
+	Label string
+	Value string
+}
+
+type htmlRow struct {
+	SKU         string
+	Name        string
+	Lot         string
+	Bucket      string
+	SoldWeight  string
+	TotalWeight string
+	SellThrough string
+}
+
+type htmlReport struct {
+	Title            string
+	Summary          []htmlSummaryItem
+	TopSKUsChart     template.HTML
+	SellThroughChart template.HTML
+	Rows             []htmlRow
+}
+
+// WriteHTML writes the report as a single HTML page with embedded styles,
+// containing a summary, a bar chart of the top SKUs by sold weight, the
+// distribution of sell-through, and the report-rows.
+func WriteHTML(w io.Writer, rep *SoldReport) error {
+	data := htmlReport{
+		Title:   "Sold-Item Report",
+		Summary: htmlSummary(rep),
+		// The charts' labels are escaped when rendering the SVG
+		TopSKUsChart: template.HTML(horizontalBarChartSVG(
+			"Top SKUs by sold weight",
+			topSKUsBySoldWeight(rep.ReportResult, HTMLTopSKUs),
+		)),
+		SellThroughChart: template.HTML(columnChartSVG(
+			"Sell-through distribution",
+			sellThroughDistribution(rep.ReportResult),
+			240,
+		)),
+		Rows: make([]htmlRow, len(rep.ReportResult)),
+	}
+	if rep.ReportID != (uuuid.UUID{}) {
+		data.Title = "Sold-Item Report " + rep.ReportID.String()
+	}
+
+	for i, r := range rep.ReportResult {
+		row := htmlRow{
+			SKU:         r.SKU,
+			Name:        r.Name,
+			Lot:         r.Lot,
+			SoldWeight:  displayNumber(r.SoldWeight),
+			TotalWeight: displayNumber(r.TotalWeight),
+			SellThrough: displayPercent(r.SellThrough()),
+		}
+		if r.Bucket != 0 {
+			row.Bucket = time.Unix(r.Bucket, 0).UTC().Format(bucketTimeFormat)
+		}
+		data.Rows[i] = row
+	}
+
+	err := htmlTemplate.Execute(w, data)
+	if err != nil {
+		return errors.Wrap(err, "Error writing HTML")
+	}
+	return nil
+}
+
+func htmlSummary(rep *SoldReport) []htmlSummaryItem {
+	summary := []htmlSummaryItem{}
+	add := func(label string, value string) {
+		summary = append(summary, htmlSummaryItem{Label: label, Value: value})
+	}
+	formatTime := func(t int64) string {
+		return time.Unix(t, 0).UTC().Format(bucketTimeFormat) + " UTC"
+	}
+
+	if rep.Timestamp != 0 {
+		add("Generated", formatTime(rep.Timestamp))
+	}
+	params := rep.SearchQuery
+	if params.Timestamp != nil {
+		if params.Timestamp.Gt != 0 {
+			add("From", formatTime(int64(params.Timestamp.Gt)))
+		}
+		if params.Timestamp.Lt != 0 {
+			add("To", formatTime(int64(params.Timestamp.Lt)))
+		}
+	}
+	filters := []struct {
+		label string
+		comp  *Comparator
+	}{
+		{"SKU", params.SKU},
+		{"Name", params.Name},
+		{"Lot", params.Lot},
+	}
+	for _, filter := range filters {
+		if filter.comp != nil && filter.comp.Eq != nil {
+			add(filter.label, fmt.Sprintf("%v", filter.comp.Eq))
+		}
+	}
+
+	add("Rows", fmt.Sprintf("%d", len(rep.ReportResult)))
+	totals := NewReportTotals(rep.ReportResult, rep.Metric)
+	if totals != nil {
+		add("Total Sold Weight", displayNumber(totals.SoldWeight))
+		add("Total Weight", displayNumber(totals.TotalWeight))
+		add("Sell-Through", displayPercent(totals.SellThrough()))
+	}
+	return summary
+}
+
+// displayPercent formats the ratio as percentage, such as "75.00%".
+func displayPercent(ratio float64) string {
+	return displayNumber(ratio*100) + "%"
+}
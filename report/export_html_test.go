@@ -0,0 +1,129 @@
+package report
+
+import (
+	"bytes"
+	"strings"
+
+	"github.com/TerrexTech/uuuid"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("HTML export", func() {
+	var rep *SoldReport
+
+	BeforeEach(func() {
+		reportID, err := uuuid.NewV4()
+		Expect(err).ToNot(HaveOccurred())
+		rep = &SoldReport{
+			ReportID: reportID,
+			SearchQuery: SoldItemParams{
+				Timestamp: &Comparator{
+					Gt: 1539302400,
+					Lt: 1539388800,
+				},
+			},
+			ReportResult: []ReportResult{
+				ReportResult{
+					SKU:         "test-sku1",
+					Name:        "<Apple>",
+					SoldWeight:  10,
+					TotalWeight: 40,
+				},
+				ReportResult{
+					SKU:         "test-sku2",
+					SoldWeight:  1200,
+					TotalWeight: 1500,
+				},
+				ReportResult{
+					SKU:         "test-sku1",
+					SoldWeight:  30,
+					TotalWeight: 40,
+				},
+			},
+			Timestamp: 1539400000,
+			Metric:    MetricSum,
+		}
+	})
+
+	It("writes a self-contained page with summary, charts and rows", func() {
+		buf := &bytes.Buffer{}
+		err := WriteHTML(buf, rep)
+		Expect(err).ToNot(HaveOccurred())
+
+		page := buf.String()
+		Expect(page).To(HavePrefix("<!DOCTYPE html>"))
+		Expect(page).To(ContainSubstring("<style>"))
+		Expect(page).To(ContainSubstring(rep.ReportID.String()))
+		Expect(page).To(ContainSubstring("<td>1,240.00</td>"))
+		Expect(page).To(ContainSubstring("<td>78.48%</td>"))
+		Expect(strings.Count(page, "<svg ")).To(Equal(2))
+		// Page is not allowed to load external resources
+		Expect(page).ToNot(ContainSubstring("src="))
+		Expect(page).ToNot(ContainSubstring("<link"))
+	})
+
+	It("writes totals of averaged reports with counts", func() {
+		rep.Metric = MetricAvg
+		for i := range rep.ReportResult {
+			rep.ReportResult[i].Count = 1
+		}
+		buf := &bytes.Buffer{}
+		err := WriteHTML(buf, rep)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(buf.String()).To(ContainSubstring("Total Sold Weight"))
+		Expect(buf.String()).To(ContainSubstring("<td>78.48%</td>"))
+	})
+
+	It("omits totals of averaged reports without counts", func() {
+		rep.Metric = MetricAvg
+		buf := &bytes.Buffer{}
+		err := WriteHTML(buf, rep)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(buf.String()).ToNot(ContainSubstring("Total Sold Weight"))
+	})
+
+	It("escapes report values", func() {
+		buf := &bytes.Buffer{}
+		err := WriteHTML(buf, rep)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(buf.String()).ToNot(ContainSubstring("<Apple>"))
+		Expect(buf.String()).To(ContainSubstring("&lt;Apple&gt;"))
+	})
+
+	It("charts the top SKUs by sold weight", func() {
+		bars := topSKUsBySoldWeight(rep.ReportResult, 10)
+		Expect(bars).To(HaveLen(2))
+		Expect(bars[0].Label).To(Equal("test-sku2"))
+		Expect(bars[1].Label).To(Equal("test-sku1"))
+		Expect(bars[1].Value).To(Equal(float64(40)))
+
+		Expect(topSKUsBySoldWeight(rep.ReportResult, 1)).To(HaveLen(1))
+	})
+
+	It("charts the distribution of sell-through", func() {
+		rep.ReportResult = append(rep.ReportResult, ReportResult{
+			SKU:         "test-sku3",
+			SoldWeight:  12,
+			TotalWeight: 10,
+		}, ReportResult{
+			SKU: "test-sku4",
+		})
+		bars := sellThroughDistribution(rep.ReportResult)
+		Expect(bars).To(HaveLen(10))
+		Expect(bars[0].Label).To(Equal("0-10%"))
+		Expect(bars[2].Value).To(Equal(float64(1)))
+		Expect(bars[7].Value).To(Equal(float64(1)))
+		Expect(bars[8].Value).To(Equal(float64(1)))
+		Expect(bars[9].Value).To(Equal(float64(1)))
+	})
+
+	It("writes a page for reports without rows", func() {
+		rep.ReportResult = nil
+		buf := &bytes.Buffer{}
+		err := WriteHTML(buf, rep)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(buf.String()).To(ContainSubstring("No rows"))
+		Expect(buf.String()).To(ContainSubstring("No data"))
+	})
+})
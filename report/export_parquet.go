@@ -0,0 +1,348 @@
+package report
+
+import (
+	"context"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/TerrexTech/go-mongoutils/mongo"
+	"github.com/TerrexTech/uuuid"
+	"github.com/mongodb/mongo-go-driver/bson"
+	"github.com/mongodb/mongo-go-driver/mongo/findopt"
+	"github.com/pkg/errors"
+	"github.com/xitongsys/parquet-go-source/local"
+	"github.com/xitongsys/parquet-go/parquet"
+	"github.com/xitongsys/parquet-go/source"
+	"github.com/xitongsys/parquet-go/writer"
+)
+
+// DefaultParquetBatchSize is the number of sold-items read from Mongo
+// per query when exporting to Parquet.
+const DefaultParquetBatchSize = 1000
+
+// parquetFileName is the name of an export's Parquet-file in each
+// day-partition. Each export has its own exportID, so exports into the same
+// directory don't overwrite each other's files.
+func parquetFileName(exportID uuuid.UUID) string {
+	return "part-" + exportID.String() + ".parquet"
+}
+
+// ParquetSoldItem is the Parquet-schema of exported sold-items. UUIDs are
+// exported as strings, and the timestamp as milliseconds since epoch.
+// The tags set the physical types, and parquetLogicalTypes annotates the
+// columns, since the tags only support the deprecated converted types.
+type ParquetSoldItem struct {
+	FlashID     string  `parquet:"name=flashID, type=BYTE_ARRAY, encoding=PLAIN_DICTIONARY"`
+	ItemID      string  `parquet:"name=itemID, type=BYTE_ARRAY, encoding=PLAIN_DICTIONARY"`
+	SaleID      string  `parquet:"name=saleID, type=BYTE_ARRAY, encoding=PLAIN_DICTIONARY"`
+	SKU         string  `parquet:"name=sku, type=BYTE_ARRAY, encoding=PLAIN_DICTIONARY"`
+	Name        string  `parquet:"name=name, type=BYTE_ARRAY, encoding=PLAIN_DICTIONARY"`
+	Lot         string  `parquet:"name=lot, type=BYTE_ARRAY, encoding=PLAIN_DICTIONARY"`
+	Weight      float64 `parquet:"name=weight, type=DOUBLE"`
+	TotalWeight float64 `parquet:"name=totalWeight, type=DOUBLE"`
+	Timestamp   int64   `parquet:"name=timestamp, type=INT64"`
+}
+
+// parquetLogicalTypes are the logical types of the ParquetSoldItem columns.
+var parquetLogicalTypes = map[string]*parquet.LogicalType{
+	"flashID": {STRING: parquet.NewStringType()},
+	"itemID":  {STRING: parquet.NewStringType()},
+	"saleID":  {STRING: parquet.NewStringType()},
+	"sku":     {STRING: parquet.NewStringType()},
+	"name":    {STRING: parquet.NewStringType()},
+	"lot":     {STRING: parquet.NewStringType()},
+	"timestamp": {
+		TIMESTAMP: &parquet.TimestampType{
+			IsAdjustedToUTC: true,
+			Unit: &parquet.TimeUnit{
+				MILLIS: parquet.NewMilliSeconds(),
+			},
+		},
+	},
+}
+
+// NewParquetSoldItem converts the sold-item to its Parquet-schema.
+func NewParquetSoldItem(item FlashSaleSoldItem) ParquetSoldItem {
+	uuidString := func(id uuuid.UUID) string {
+		if id == (uuuid.UUID{}) {
+			return ""
+		}
+		return id.String()
+	}
+	return ParquetSoldItem{
+		FlashID:     uuidString(item.FlashID),
+		ItemID:      uuidString(item.ItemID),
+		SaleID:      uuidString(item.SaleID),
+		SKU:         item.SKU,
+		Name:        item.Name,
+		Lot:         item.Lot,
+		Weight:      item.Weight,
+		TotalWeight: item.TotalWeight,
+		Timestamp:   item.Timestamp * 1000,
+	}
+}
+
+// ParquetExportOptions configure the Parquet export of sold-items.
+type ParquetExportOptions struct {
+	// Filter selects the exported sold-items. All sold-items are exported
+	// if no filter is set.
+	Filter SoldItemParams `json:"filter"`
+	// OutputDir is the directory the day-partitions are written to. It is
+	// created if it doesn't exist.
+	OutputDir string `json:"outputDir"`
+	// BatchSize is the number of sold-items read per query. Defaults to
+	// DefaultParquetBatchSize.
+	BatchSize int64 `json:"batchSize,omitempty"`
+}
+
+// ParquetExportResult lists the files written by a Parquet export.
+type ParquetExportResult struct {
+	Files   []string `json:"files"`
+	Records int64    `json:"records"`
+}
+
+// parquetRowWriter writes sold-items to a single Parquet-file.
+type parquetRowWriter interface {
+	Write(item ParquetSoldItem) error
+	Close() error
+}
+
+// parquetFileWriter writes sold-items to a local Parquet-file.
+type parquetFileWriter struct {
+	file source.ParquetFile
+	pw   *writer.ParquetWriter
+}
+
+func newParquetFileWriter(path string) (parquetRowWriter, error) {
+	file, err := local.NewLocalFileWriter(path)
+	if err != nil {
+		err = errors.Wrapf(err, "Error creating Parquet-file: %s", path)
+		return nil, err
+	}
+	pw, err := writer.NewParquetWriter(file, new(ParquetSoldItem), 1)
+	if err != nil {
+		file.Close()
+		err = errors.Wrap(err, "Error creating Parquet-writer")
+		return nil, err
+	}
+	// The footer references the same schema-elements. Their names are only
+	// set to the column-names when the footer is written.
+	for i, element := range pw.SchemaHandler.SchemaElements {
+		element.LogicalType = parquetLogicalTypes[pw.SchemaHandler.Infos[i].ExName]
+	}
+	pw.CompressionType = parquet.CompressionCodec_SNAPPY
+	return &parquetFileWriter{
+		file: file,
+		pw:   pw,
+	}, nil
+}
+
+func (w *parquetFileWriter) Write(item ParquetSoldItem) error {
+	err := w.pw.Write(item)
+	if err != nil {
+		return errors.Wrap(err, "Error writing Parquet-row")
+	}
+	return nil
+}
+
+// Close writes the Parquet-footer and closes the file.
+func (w *parquetFileWriter) Close() error {
+	err := w.pw.WriteStop()
+	if err != nil {
+		w.file.Close()
+		return errors.Wrap(err, "Error writing Parquet-footer")
+	}
+	err = w.file.Close()
+	if err != nil {
+		return errors.Wrap(err, "Error closing Parquet-file")
+	}
+	return nil
+}
+
+// parquetExporter writes sold-items into day-partitioned Parquet-files.
+// Sold-items are expected in ascending order of their timestamp, so only the
+// current day's file is open at any time.
+type parquetExporter struct {
+	outputDir string
+	fileName  string
+	newWriter func(path string) (parquetRowWriter, error)
+
+	day    string
+	writer parquetRowWriter
+	result ParquetExportResult
+}
+
+// partitionDir is the Hive-style directory of the day-partition, such as
+// "date=2018-10-12".
+func partitionDir(timestamp int64) string {
+	return "date=" + time.Unix(timestamp, 0).UTC().Format("2006-01-02")
+}
+
+func (e *parquetExporter) write(item FlashSaleSoldItem) error {
+	day := partitionDir(item.Timestamp)
+	if e.writer == nil || day != e.day {
+		err := e.close()
+		if err != nil {
+			return err
+		}
+
+		dir := filepath.Join(e.outputDir, day)
+		err = os.MkdirAll(dir, 0755)
+		if err != nil {
+			err = errors.Wrapf(err, "Error creating partition-directory: %s", dir)
+			return err
+		}
+		path := filepath.Join(dir, e.fileName)
+		e.writer, err = e.newWriter(path)
+		if err != nil {
+			return err
+		}
+		e.day = day
+		e.result.Files = append(e.result.Files, path)
+	}
+
+	err := e.writer.Write(NewParquetSoldItem(item))
+	if err != nil {
+		return err
+	}
+	e.result.Records++
+	return nil
+}
+
+// close closes the current day's file, if any.
+func (e *parquetExporter) close() error {
+	if e.writer == nil {
+		return nil
+	}
+	err := e.writer.Close()
+	e.writer = nil
+	return err
+}
+
+// ExportParquet streams the sold-items matching the filter into Parquet-files,
+// partitioned by day into directories such as "date=2018-10-12". Sold-items
+// are read in batches, so the export doesn't hold all records in memory.
+// Each export writes new files, named by a unique exportID, so files of
+// earlier exports are kept. If ctx is done first, the export stops with the
+// ctx-error, and the files written so far are closed.
+func ExportParquet(
+	ctx context.Context,
+	opts ParquetExportOptions,
+	itemSoldColl *mongo.Collection,
+) (*ParquetExportResult, error) {
+	if opts.OutputDir == "" {
+		return nil, errors.New("Parquet export requires an output-directory")
+	}
+	if opts.BatchSize <= 0 {
+		opts.BatchSize = DefaultParquetBatchSize
+	}
+
+	filter, err := soldItemFilter(opts.Filter)
+	if err != nil {
+		return nil, err
+	}
+	exportID, err := uuuid.NewV4()
+	if err != nil {
+		err = errors.Wrap(err, "Error generating exportID")
+		return nil, err
+	}
+	exporter := &parquetExporter{
+		outputDir: opts.OutputDir,
+		fileName:  parquetFileName(exportID),
+		newWriter: newParquetFileWriter,
+	}
+
+	// Batches continue after the last exported sold-item, instead of
+	// skipping the exported ones, which Mongo would have to scan again.
+	var last *FlashSaleSoldItem
+	for {
+		batchFilter := filter
+		if last != nil {
+			batchFilter = map[string]interface{}{
+				"$and": []interface{}{filter, soldItemsAfter(last)},
+			}
+		}
+		// Sorting by _id as well keeps the order stable across batches. The
+		// sort-keys are ordered, so a bson.Document is used instead of a map.
+		var findResult []interface{}
+		err := runMongoOp(ctx, func() error {
+			var err error
+			findResult, err = itemSoldColl.Find(
+				batchFilter,
+				findopt.Sort(bson.NewDocument(
+					bson.EC.Int32("timestamp", 1),
+					bson.EC.Int32("_id", 1),
+				)),
+				findopt.Limit(opts.BatchSize),
+			)
+			return err
+		})
+		if err != nil {
+			exporter.close()
+			err = errors.Wrap(err, "Error finding sold-items")
+			return nil, err
+		}
+
+		for _, v := range findResult {
+			item, assertOK := v.(*FlashSaleSoldItem)
+			if !assertOK {
+				exporter.close()
+				return nil, errors.New("Error asserting sold-item to FlashSaleSoldItem")
+			}
+			err = exporter.write(*item)
+			if err != nil {
+				exporter.close()
+				return nil, err
+			}
+			last = item
+		}
+		if int64(len(findResult)) < opts.BatchSize {
+			break
+		}
+	}
+
+	err = exporter.close()
+	if err != nil {
+		return nil, err
+	}
+	return &exporter.result, nil
+}
+
+// soldItemsAfter filters the sold-items that come after the item, in the
+// order of their timestamp and _id.
+func soldItemsAfter(item *FlashSaleSoldItem) map[string]interface{} {
+	return map[string]interface{}{
+		"$or": []interface{}{
+			map[string]interface{}{
+				"timestamp": map[string]interface{}{
+					"$gt": item.Timestamp,
+				},
+			},
+			map[string]interface{}{
+				"timestamp": item.Timestamp,
+				"_id": map[string]interface{}{
+					"$gt": item.ID,
+				},
+			},
+		},
+	}
+}
+
+// soldItemFilter converts the search-params to a Find-filter. The params'
+// JSON-tags are the Mongo query-operators, as used in aggregations.
+func soldItemFilter(params SoldItemParams) (map[string]interface{}, error) {
+	paramsJSON, err := json.Marshal(params)
+	if err != nil {
+		err = errors.Wrap(err, "Error marshalling search-params")
+		return nil, err
+	}
+	filter := map[string]interface{}{}
+	err = json.Unmarshal(paramsJSON, &filter)
+	if err != nil {
+		err = errors.Wrap(err, "Error converting search-params to filter")
+		return nil, err
+	}
+	return filter, nil
+}
@@ -0,0 +1,223 @@
+package report
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+
+	"github.com/TerrexTech/uuuid"
+	"github.com/mongodb/mongo-go-driver/bson/objectid"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	"github.com/xitongsys/parquet-go-source/local"
+	"github.com/xitongsys/parquet-go/parquet"
+	"github.com/xitongsys/parquet-go/reader"
+)
+
+// memParquetWriter keeps the written rows in memory.
+type memParquetWriter struct {
+	rows   []ParquetSoldItem
+	closed bool
+}
+
+func (w *memParquetWriter) Write(item ParquetSoldItem) error {
+	w.rows = append(w.rows, item)
+	return nil
+}
+
+func (w *memParquetWriter) Close() error {
+	w.closed = true
+	return nil
+}
+
+var _ = Describe("Parquet export", func() {
+	var (
+		outputDir string
+		exporter  *parquetExporter
+		writers   map[string]*memParquetWriter
+	)
+
+	BeforeEach(func() {
+		var err error
+		outputDir, err = ioutil.TempDir("", "parquet-export")
+		Expect(err).ToNot(HaveOccurred())
+
+		writers = map[string]*memParquetWriter{}
+		exporter = &parquetExporter{
+			outputDir: outputDir,
+			fileName:  "part-test.parquet",
+			newWriter: func(path string) (parquetRowWriter, error) {
+				w := &memParquetWriter{}
+				writers[path] = w
+				return w, nil
+			},
+		}
+	})
+
+	AfterEach(func() {
+		os.RemoveAll(outputDir)
+	})
+
+	It("converts UUIDs to strings and timestamps to milliseconds", func() {
+		flashID, err := uuuid.NewV4()
+		Expect(err).ToNot(HaveOccurred())
+		item := NewParquetSoldItem(FlashSaleSoldItem{
+			FlashID:   flashID,
+			SKU:       "test-sku",
+			Weight:    12.5,
+			Timestamp: 1539302400,
+		})
+		Expect(item.FlashID).To(Equal(flashID.String()))
+		Expect(item.ItemID).To(BeEmpty())
+		Expect(item.SKU).To(Equal("test-sku"))
+		Expect(item.Weight).To(Equal(12.5))
+		Expect(item.Timestamp).To(Equal(int64(1539302400000)))
+	})
+
+	It("partitions sold-items by day", func() {
+		timestamps := []int64{
+			// 2018-10-12
+			1539302400, 1539388799,
+			// 2018-10-13
+			1539388800,
+		}
+		for _, ts := range timestamps {
+			err := exporter.write(FlashSaleSoldItem{Timestamp: ts})
+			Expect(err).ToNot(HaveOccurred())
+		}
+		err := exporter.close()
+		Expect(err).ToNot(HaveOccurred())
+
+		day1 := filepath.Join(outputDir, "date=2018-10-12", "part-test.parquet")
+		day2 := filepath.Join(outputDir, "date=2018-10-13", "part-test.parquet")
+		Expect(exporter.result.Files).To(Equal([]string{day1, day2}))
+		Expect(exporter.result.Records).To(Equal(int64(3)))
+
+		Expect(writers[day1].rows).To(HaveLen(2))
+		Expect(writers[day1].closed).To(BeTrue())
+		Expect(writers[day2].rows).To(HaveLen(1))
+		Expect(writers[day2].closed).To(BeTrue())
+
+		_, err = os.Stat(filepath.Dir(day2))
+		Expect(err).ToNot(HaveOccurred())
+	})
+
+	It("names the files of each export uniquely", func() {
+		exportID1, err := uuuid.NewV4()
+		Expect(err).ToNot(HaveOccurred())
+		exportID2, err := uuuid.NewV4()
+		Expect(err).ToNot(HaveOccurred())
+		Expect(parquetFileName(exportID1)).ToNot(Equal(parquetFileName(exportID2)))
+	})
+
+	It("writes files readable with the physical and logical types", func() {
+		flashID, err := uuuid.NewV4()
+		Expect(err).ToNot(HaveOccurred())
+		rows := []ParquetSoldItem{
+			NewParquetSoldItem(FlashSaleSoldItem{
+				FlashID:     flashID,
+				SKU:         "test-sku1",
+				Name:        "test-name",
+				Weight:      12.5,
+				TotalWeight: 20,
+				Timestamp:   1539302400,
+			}),
+			NewParquetSoldItem(FlashSaleSoldItem{
+				SKU:       "test-sku2",
+				Lot:       "test-lot",
+				Weight:    3,
+				Timestamp: 1539302401,
+			}),
+		}
+
+		path := filepath.Join(outputDir, "part-test.parquet")
+		w, err := newParquetFileWriter(path)
+		Expect(err).ToNot(HaveOccurred())
+		for _, row := range rows {
+			Expect(w.Write(row)).To(Succeed())
+		}
+		Expect(w.Close()).To(Succeed())
+
+		file, err := local.NewLocalFileReader(path)
+		Expect(err).ToNot(HaveOccurred())
+		defer file.Close()
+		pr, err := reader.NewParquetReader(file, new(ParquetSoldItem), 1)
+		Expect(err).ToNot(HaveOccurred())
+		defer pr.ReadStop()
+
+		readRows := make([]ParquetSoldItem, pr.GetNumRows())
+		Expect(pr.Read(&readRows)).To(Succeed())
+		Expect(readRows).To(Equal(rows))
+
+		// The footer is read again, since the reader renames its columns
+		footerFile, err := local.NewLocalFileReader(path)
+		Expect(err).ToNot(HaveOccurred())
+		defer footerFile.Close()
+		footerReader := &reader.ParquetReader{PFile: footerFile}
+		Expect(footerReader.ReadFooter()).To(Succeed())
+
+		columns := map[string]*parquet.SchemaElement{}
+		for _, element := range footerReader.Footer.Schema {
+			columns[element.GetName()] = element
+		}
+		sku := columns["sku"]
+		Expect(sku.GetType()).To(Equal(parquet.Type_BYTE_ARRAY))
+		Expect(sku.IsSetConvertedType()).To(BeFalse())
+		Expect(sku.GetLogicalType().IsSetSTRING()).To(BeTrue())
+
+		timestamp := columns["timestamp"]
+		Expect(timestamp.GetType()).To(Equal(parquet.Type_INT64))
+		Expect(timestamp.IsSetConvertedType()).To(BeFalse())
+		Expect(timestamp.GetLogicalType().IsSetTIMESTAMP()).To(BeTrue())
+		Expect(timestamp.GetLogicalType().GetTIMESTAMP().GetIsAdjustedToUTC()).To(BeTrue())
+		Expect(timestamp.GetLogicalType().GetTIMESTAMP().GetUnit().IsSetMILLIS()).To(BeTrue())
+
+		Expect(columns["weight"].GetType()).To(Equal(parquet.Type_DOUBLE))
+		Expect(columns["weight"].IsSetLogicalType()).To(BeFalse())
+	})
+
+	It("continues batches after the last exported sold-item", func() {
+		id, err := objectid.FromHex("5bc0a1f2e1b2c3d4e5f60718")
+		Expect(err).ToNot(HaveOccurred())
+		Expect(soldItemsAfter(&FlashSaleSoldItem{
+			ID:        id,
+			Timestamp: 1539302400,
+		})).To(Equal(map[string]interface{}{
+			"$or": []interface{}{
+				map[string]interface{}{
+					"timestamp": map[string]interface{}{
+						"$gt": int64(1539302400),
+					},
+				},
+				map[string]interface{}{
+					"timestamp": int64(1539302400),
+					"_id": map[string]interface{}{
+						"$gt": id,
+					},
+				},
+			},
+		}))
+	})
+
+	It("converts search-params to a Find-filter", func() {
+		filter, err := soldItemFilter(SoldItemParams{
+			Timestamp: &Comparator{
+				Gt: 1539302400,
+				Lt: 1539388800,
+			},
+			SKU: &Comparator{
+				Eq: "test-sku",
+			},
+		})
+		Expect(err).ToNot(HaveOccurred())
+		Expect(filter).To(Equal(map[string]interface{}{
+			"timestamp": map[string]interface{}{
+				"$gt": float64(1539302400),
+				"$lt": float64(1539388800),
+			},
+			"sku": map[string]interface{}{
+				"$eq": "test-sku",
+			},
+		}))
+	})
+})
@@ -0,0 +1,215 @@
+package report
+
+import (
+	"fmt"
+	"io"
+	"sort"
+	"time"
+
+	"github.com/360EntSecGroup-Skylar/excelize"
+	"github.com/TerrexTech/uuuid"
+	"github.com/pkg/errors"
+)
+
+// Sheets of XLSX exports.
+const (
+	xlsxSummarySheet = "Summary"
+	xlsxDataSheet    = "Data"
+)
+
+// MaxXLSXBucketSheets is the max number of per-bucket sheets in XLSX exports.
+// Reports with more time-buckets only have their rows on the data-sheet.
+const MaxXLSXBucketSheets = 100
+
+// xlsxColumns are the headers of data-sheets, in order.
+var xlsxColumns = []string{
+	"SKU", "Name", "Lot", "Bucket", "Sold Weight", "Total Weight", "Sell-Through",
+}
+
+// xlsxStyles are the cell-styles used in XLSX exports.
+type xlsxStyles struct {
+	header   int
+	number   int
+	percent  int
+	dateTime int
+}
+
+func newXLSXStyles(f *excelize.File) (*xlsxStyles, error) {
+	styles := &xlsxStyles{}
+	var err error
+	styles.header, err = f.NewStyle(`{"font":{"bold":true}}`)
+	if err != nil {
+		return nil, errors.Wrap(err, "Error creating header-style")
+	}
+	// "#,##0.00"
+	styles.number, err = f.NewStyle(`{"number_format":4}`)
+	if err != nil {
+		return nil, errors.Wrap(err, "Error creating number-style")
+	}
+	// "0.00%"
+	styles.percent, err = f.NewStyle(`{"number_format":10}`)
+	if err != nil {
+		return nil, errors.Wrap(err, "Error creating percent-style")
+	}
+	styles.dateTime, err = f.NewStyle(`{"custom_number_format":"yyyy-mm-dd hh:mm"}`)
+	if err != nil {
+		return nil, errors.Wrap(err, "Error creating date-style")
+	}
+	return styles, nil
+}
+
+// WriteXLSX writes the report as XLSX workbook. The workbook has a summary
+// sheet with the search-params and totals, and a data-sheet with the
+// report-rows. Bucketed reports have an additional sheet per time-bucket.
+func WriteXLSX(w io.Writer, rep *SoldReport) error {
+	f := excelize.NewFile()
+	styles, err := newXLSXStyles(f)
+	if err != nil {
+		return err
+	}
+
+	f.SetSheetName("Sheet1", xlsxSummarySheet)
+	writeXLSXSummary(f, styles, rep)
+
+	f.NewSheet(xlsxDataSheet)
+	writeXLSXRows(f, styles, xlsxDataSheet, rep.ReportResult)
+
+	buckets, bucketRows := groupRowsByBucket(rep.ReportResult)
+	if len(buckets) <= MaxXLSXBucketSheets {
+		for _, bucket := range buckets {
+			sheet := bucketSheetName(bucket)
+			f.NewSheet(sheet)
+			writeXLSXRows(f, styles, sheet, bucketRows[bucket])
+		}
+	}
+
+	f.SetActiveSheet(f.GetSheetIndex(xlsxSummarySheet))
+	err = f.Write(w)
+	if err != nil {
+		return errors.Wrap(err, "Error writing XLSX")
+	}
+	return nil
+}
+
+func writeXLSXSummary(f *excelize.File, styles *xlsxStyles, rep *SoldReport) {
+	sheet := xlsxSummarySheet
+	row := 0
+	addRow := func(label string, value interface{}, style int) {
+		row++
+		labelCell := fmt.Sprintf("A%d", row)
+		valueCell := fmt.Sprintf("B%d", row)
+		f.SetCellValue(sheet, labelCell, label)
+		f.SetCellStyle(sheet, labelCell, labelCell, styles.header)
+		f.SetCellValue(sheet, valueCell, value)
+		if style != 0 {
+			f.SetCellStyle(sheet, valueCell, valueCell, style)
+		}
+	}
+
+	if rep.ReportID != (uuuid.UUID{}) {
+		addRow("Report ID", rep.ReportID.String(), 0)
+	}
+	if rep.Timestamp != 0 {
+		addRow("Generated", time.Unix(rep.Timestamp, 0).UTC(), styles.dateTime)
+	}
+
+	params := rep.SearchQuery
+	if params.Timestamp != nil {
+		if params.Timestamp.Gt != 0 {
+			from := time.Unix(int64(params.Timestamp.Gt), 0).UTC()
+			addRow("From", from, styles.dateTime)
+		}
+		if params.Timestamp.Lt != 0 {
+			to := time.Unix(int64(params.Timestamp.Lt), 0).UTC()
+			addRow("To", to, styles.dateTime)
+		}
+	}
+	filters := []struct {
+		label string
+		comp  *Comparator
+	}{
+		{"SKU", params.SKU},
+		{"Name", params.Name},
+		{"Lot", params.Lot},
+	}
+	for _, filter := range filters {
+		if filter.comp != nil && filter.comp.Eq != nil {
+			addRow(filter.label, fmt.Sprintf("%v", filter.comp.Eq), 0)
+		}
+	}
+
+	addRow("Rows", len(rep.ReportResult), 0)
+	totals := NewReportTotals(rep.ReportResult, rep.Metric)
+	if totals != nil {
+		addRow("Total Sold Weight", totals.SoldWeight, styles.number)
+		addRow("Total Weight", totals.TotalWeight, styles.number)
+		addRow("Sell-Through", totals.SellThrough(), styles.percent)
+	}
+
+	f.SetColWidth(sheet, "A", "A", 20)
+	f.SetColWidth(sheet, "B", "B", 40)
+}
+
+func writeXLSXRows(f *excelize.File, styles *xlsxStyles, sheet string, rows []ReportResult) {
+	for i, header := range xlsxColumns {
+		cell := excelize.ToAlphaString(i) + "1"
+		f.SetCellValue(sheet, cell, header)
+	}
+	lastCol := excelize.ToAlphaString(len(xlsxColumns) - 1)
+	f.SetCellStyle(sheet, "A1", lastCol+"1", styles.header)
+
+	for i, r := range rows {
+		row := i + 2
+		f.SetCellValue(sheet, fmt.Sprintf("A%d", row), r.SKU)
+		f.SetCellValue(sheet, fmt.Sprintf("B%d", row), r.Name)
+		f.SetCellValue(sheet, fmt.Sprintf("C%d", row), r.Lot)
+		if r.Bucket != 0 {
+			f.SetCellValue(sheet, fmt.Sprintf("D%d", row), time.Unix(r.Bucket, 0).UTC())
+		}
+		f.SetCellValue(sheet, fmt.Sprintf("E%d", row), r.SoldWeight)
+		f.SetCellValue(sheet, fmt.Sprintf("F%d", row), r.TotalWeight)
+		f.SetCellValue(sheet, fmt.Sprintf("G%d", row), r.SellThrough())
+	}
+
+	if len(rows) > 0 {
+		lastRow := len(rows) + 1
+		f.SetCellStyle(sheet, "D2", fmt.Sprintf("D%d", lastRow), styles.dateTime)
+		f.SetCellStyle(sheet, "E2", fmt.Sprintf("F%d", lastRow), styles.number)
+		f.SetCellStyle(sheet, "G2", fmt.Sprintf("G%d", lastRow), styles.percent)
+	}
+	f.SetColWidth(sheet, "A", "C", 16)
+	f.SetColWidth(sheet, "D", "D", 18)
+	f.SetColWidth(sheet, "E", "G", 14)
+	// Freeze the header-row
+	f.SetPanes(sheet, `{"freeze":true,"y_split":1,"top_left_cell":"A2","active_pane":"bottomLeft"}`)
+}
+
+// groupRowsByBucket groups the rows by their time-bucket. The buckets are
+// returned in ascending order. Rows without bucket are not grouped.
+func groupRowsByBucket(rows []ReportResult) ([]int64, map[int64][]ReportResult) {
+	bucketRows := map[int64][]ReportResult{}
+	buckets := []int64{}
+	for _, r := range rows {
+		if r.Bucket == 0 {
+			continue
+		}
+		if _, ok := bucketRows[r.Bucket]; !ok {
+			buckets = append(buckets, r.Bucket)
+		}
+		bucketRows[r.Bucket] = append(bucketRows[r.Bucket], r)
+	}
+	sort.Slice(buckets, func(i, j int) bool {
+		return buckets[i] < buckets[j]
+	})
+	return buckets, bucketRows
+}
+
+// bucketSheetName is the sheet-name for the time-bucket. Sheet-names cannot
+// contain ":", so hours are formatted as "15h".
+func bucketSheetName(bucket int64) string {
+	t := time.Unix(bucket, 0).UTC()
+	if t.Hour() == 0 {
+		return t.Format("2006-01-02")
+	}
+	return t.Format("2006-01-02 15h")
+}
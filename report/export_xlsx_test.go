@@ -0,0 +1,137 @@
+package report
+
+import (
+	"bytes"
+
+	"github.com/360EntSecGroup-Skylar/excelize"
+	"github.com/TerrexTech/uuuid"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("XLSX export", func() {
+	var rep *SoldReport
+
+	BeforeEach(func() {
+		reportID, err := uuuid.NewV4()
+		Expect(err).ToNot(HaveOccurred())
+		rep = &SoldReport{
+			ReportID: reportID,
+			SearchQuery: SoldItemParams{
+				Timestamp: &Comparator{
+					Gt: 1539302400,
+					Lt: 1539475200,
+				},
+				Lot: &Comparator{
+					Eq: "test-lot",
+				},
+			},
+			ReportResult: []ReportResult{
+				ReportResult{
+					SKU:         "test-sku1",
+					Bucket:      1539388800,
+					SoldWeight:  30,
+					TotalWeight: 40,
+				},
+				ReportResult{
+					SKU:         "test-sku1",
+					Bucket:      1539302400,
+					SoldWeight:  10,
+					TotalWeight: 40,
+				},
+				ReportResult{
+					SKU:         "test-sku2",
+					Bucket:      1539302400,
+					SoldWeight:  20,
+					TotalWeight: 20,
+				},
+			},
+			Timestamp: 1539500000,
+			Metric:    MetricSum,
+		}
+	})
+
+	readXLSX := func(rep *SoldReport) *excelize.File {
+		buf := &bytes.Buffer{}
+		err := WriteXLSX(buf, rep)
+		Expect(err).ToNot(HaveOccurred())
+		f, err := excelize.OpenReader(buf)
+		Expect(err).ToNot(HaveOccurred())
+		return f
+	}
+
+	It("writes summary, data and per-bucket sheets", func() {
+		f := readXLSX(rep)
+		sheets := []string{}
+		for i := 1; i <= len(f.GetSheetMap()); i++ {
+			sheets = append(sheets, f.GetSheetMap()[i])
+		}
+		Expect(sheets).To(Equal([]string{
+			"Summary", "Data", "2018-10-12", "2018-10-13",
+		}))
+
+		Expect(f.GetRows("Data")).To(HaveLen(4))
+		Expect(f.GetRows("2018-10-12")).To(HaveLen(3))
+		Expect(f.GetRows("2018-10-13")).To(HaveLen(2))
+	})
+
+	It("writes typed numeric columns", func() {
+		f := readXLSX(rep)
+		Expect(f.GetCellValue("Data", "A1")).To(Equal("SKU"))
+		Expect(f.GetCellValue("Data", "G1")).To(Equal("Sell-Through"))
+
+		// Cells are read back using their number-formats
+		Expect(f.GetCellValue("Data", "E2")).To(Equal("30.00"))
+		Expect(f.GetCellValue("Data", "F2")).To(Equal("40.00"))
+		Expect(f.GetCellValue("Data", "G2")).To(Equal("75.00%"))
+	})
+
+	It("writes params and totals to the summary sheet", func() {
+		f := readXLSX(rep)
+		summary := map[string]string{}
+		for _, row := range f.GetRows("Summary") {
+			summary[row[0]] = row[1]
+		}
+		Expect(summary["Report ID"]).To(Equal(rep.ReportID.String()))
+		Expect(summary["Lot"]).To(Equal("test-lot"))
+		Expect(summary["Rows"]).To(Equal("3"))
+		Expect(summary["Total Sold Weight"]).To(Equal("60.00"))
+		Expect(summary["Total Weight"]).To(Equal("100.00"))
+		Expect(summary["Sell-Through"]).To(Equal("60.00%"))
+	})
+
+	It("weights averaged rows by their counts in the summary", func() {
+		rep.Metric = MetricAvg
+		for i := range rep.ReportResult {
+			rep.ReportResult[i].Count = 2
+		}
+		f := readXLSX(rep)
+		summary := map[string]string{}
+		for _, row := range f.GetRows("Summary") {
+			summary[row[0]] = row[1]
+		}
+		Expect(summary["Total Sold Weight"]).To(Equal("120.00"))
+		Expect(summary["Total Weight"]).To(Equal("200.00"))
+		Expect(summary["Sell-Through"]).To(Equal("60.00%"))
+	})
+
+	It("omits totals from the summary of averaged reports without counts", func() {
+		rep.Metric = ""
+		f := readXLSX(rep)
+		summary := map[string]string{}
+		for _, row := range f.GetRows("Summary") {
+			summary[row[0]] = row[1]
+		}
+		Expect(summary["Rows"]).To(Equal("3"))
+		Expect(summary).ToNot(HaveKey("Total Sold Weight"))
+		Expect(summary).ToNot(HaveKey("Sell-Through"))
+	})
+
+	It("does not add bucket sheets for unbucketed reports", func() {
+		for i := range rep.ReportResult {
+			rep.ReportResult[i].Bucket = 0
+		}
+		f := readXLSX(rep)
+		Expect(f.GetSheetMap()).To(HaveLen(2))
+	})
+})
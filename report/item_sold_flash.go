@@ -1,74 +1,259 @@
 package report
 
 import (
-	"encoding/json"
-	"fmt"
+	"context"
 	"log"
 
 	"github.com/TerrexTech/go-mongoutils/mongo"
-	"github.com/mongodb/mongo-go-driver/bson"
+	"github.com/TerrexTech/uuuid"
 	mgo "github.com/mongodb/mongo-go-driver/mongo"
+	"github.com/mongodb/mongo-go-driver/mongo/findopt"
 	"github.com/pkg/errors"
 )
 
-func ItemSoldReport(aggParams SoldItemParams, itemSoldColl *mongo.Collection) ([]interface{}, error) {
+// ItemSoldReport aggregates the average sold and total weights of sold-items
+// matching the search-params, grouped by SKU and Name.
+func ItemSoldReport(
+	ctx context.Context,
+	aggParams SoldItemParams,
+	itemSoldColl *mongo.Collection,
+) ([]interface{}, error) {
+	return Aggregate(ctx, aggParams, DefaultAggregation(), itemSoldColl)
+}
+
+// ErrReportIDConflict is returned when the reportID of a new report is
+// already used by a different stored report.
+var ErrReportIDConflict = errors.New("reportID is used by a different report")
 
-	if aggParams.Timestamp.Lt == 0 || aggParams.Timestamp.Gt == 0 {
-		err := errors.New("Missing timestamp value")
+// CreateReport stores the generated report. If ctx is done first, the
+// ctx-error is returned, though the insert may still complete in background.
+// The reportID is unique, so if an insert succeeded but its reply was lost,
+// its retry fails with a duplicate-key error. The stored report is compared
+// in that case, and if it matches, the report counts as stored and the
+// InsertOneResult is nil. Otherwise ErrReportIDConflict is returned.
+func CreateReport(
+	ctx context.Context,
+	reportGen SoldReport,
+	reportColl *mongo.Collection,
+) (*mgo.InsertOneResult, error) {
+	var insertRep *mgo.InsertOneResult
+	attempts := 0
+	retriedDuplicate := false
+	err := runMongoOp(ctx, func() error {
+		attempts++
+		var err error
+		insertRep, err = reportColl.InsertOne(reportGen)
+		if err != nil && attempts > 1 && isDuplicateKeyError(err) {
+			retriedDuplicate = true
+			return nil
+		}
+		return err
+	})
+	if err != nil {
+		err = errors.Wrap(err, "Query: Error in generating report ")
 		log.Println(err)
 		return nil, err
 	}
-	input, err := json.Marshal(aggParams)
+	if !retriedDuplicate {
+		return insertRep, nil
+	}
+
+	stored, err := FindReport(ctx, reportGen.ReportID, reportColl)
 	if err != nil {
-		err = errors.Wrap(err, "Unable to marshal aggParams")
+		err = errors.Wrap(err, "Query: Error in finding report after duplicate-key error")
 		log.Println(err)
 		return nil, err
 	}
+	if !sameReport(stored, &reportGen) {
+		err = errors.Wrapf(ErrReportIDConflict, "Query: Error in generating report %s", reportGen.ReportID)
+		log.Println(err)
+		return nil, err
+	}
+	return nil, nil
+}
 
-	log.Println(input)
-	log.Println(aggParams)
-
-	pipelineBuilder := fmt.Sprintf(`[
-		{
-			"$match": %s
-		},
-		{
-			"$group" : {
-			"_id" : {"sku" : "$sku","name":"$name"},
-			"avg_sold": {
-				"$avg": "$weight",
-			},
-			"avg_total": {
-				"$avg": "$totalWeight",
-			}
-		}
+// sameReport returns true if both reports have the same reportID, timestamp,
+// metric and rows. Only fields that survive the round-trip through Mongo
+// unchanged are compared.
+func sameReport(a *SoldReport, b *SoldReport) bool {
+	if a.ReportID != b.ReportID ||
+		a.Timestamp != b.Timestamp ||
+		a.Metric != b.Metric ||
+		a.Empty != b.Empty ||
+		len(a.ReportResult) != len(b.ReportResult) {
+		return false
+	}
+	for i := range a.ReportResult {
+		if a.ReportResult[i] != b.ReportResult[i] {
+			return false
 		}
-	]`, input)
+	}
+	return true
+}
+
+// ErrReportNotFound is returned when no report matches the provided reportID.
+var ErrReportNotFound = errors.New("report not found")
+
+// HistoryParams are the paging-parameters for listing generated reports.
+type HistoryParams struct {
+	Limit int64 `json:"limit,omitempty"`
+	Skip  int64 `json:"skip,omitempty"`
+}
+
+// DefaultHistoryLimit is the number of reports listed when no limit is provided.
+const DefaultHistoryLimit = 20
 
-	log.Println(pipelineBuilder)
+// MaxHistoryLimit is the maximum number of reports listed at once. Larger
+// limits are reduced to it.
+const MaxHistoryLimit = 100
 
-	pipelineAgg, err := bson.ParseExtJSONArray(pipelineBuilder)
+// FindReport returns the stored report with the specified reportID. If ctx
+// is done first, the ctx-error is returned.
+func FindReport(
+	ctx context.Context,
+	reportID uuuid.UUID,
+	reportColl *mongo.Collection,
+) (*SoldReport, error) {
+	var findResult []interface{}
+	err := runMongoOp(ctx, func() error {
+		var err error
+		findResult, err = reportColl.Find(map[string]interface{}{
+			"reportID": map[string]interface{}{
+				"$eq": reportID.String(),
+			},
+		})
+		return err
+	})
 	if err != nil {
-		err = errors.Wrap(err, "Query: Error in generating pipeline for report")
+		err = errors.Wrap(err, "Query: Error in finding report")
 		log.Println(err)
 		return nil, err
 	}
+	if len(findResult) == 0 {
+		return nil, ErrReportNotFound
+	}
 
-	findResult, err := itemSoldColl.Aggregate(pipelineAgg)
-	if err != nil {
-		err = errors.Wrap(err, "Query: Error in getting aggregate results ")
+	rep, assertOK := findResult[0].(*SoldReport)
+	if !assertOK {
+		err = errors.New("Query: Error asserting report to SoldReport")
 		log.Println(err)
 		return nil, err
 	}
-	return findResult, nil
+	return rep, nil
 }
 
-func CreateReport(reportGen SoldReport, reportColl *mongo.Collection) (*mgo.InsertOneResult, error) {
-	insertRep, err := reportColl.InsertOne(reportGen)
+// ListReports returns the stored reports, newest first. If ctx is done
+// first, the ctx-error is returned.
+func ListReports(
+	ctx context.Context,
+	params HistoryParams,
+	reportColl *mongo.Collection,
+) ([]SoldReport, error) {
+	if params.Limit <= 0 {
+		params.Limit = DefaultHistoryLimit
+	}
+	if params.Limit > MaxHistoryLimit {
+		params.Limit = MaxHistoryLimit
+	}
+	if params.Skip < 0 {
+		params.Skip = 0
+	}
+
+	var findResult []interface{}
+	err := runMongoOp(ctx, func() error {
+		var err error
+		findResult, err = reportColl.Find(
+			map[string]interface{}{},
+			findopt.Sort(map[string]interface{}{
+				"timestamp": -1,
+			}),
+			findopt.Skip(params.Skip),
+			findopt.Limit(params.Limit),
+		)
+		return err
+	})
 	if err != nil {
-		err = errors.Wrap(err, "Query: Error in generating report ")
+		err = errors.Wrap(err, "Query: Error in listing reports")
 		log.Println(err)
 		return nil, err
 	}
-	return insertRep, nil
+
+	reports := make([]SoldReport, 0, len(findResult))
+	for _, v := range findResult {
+		rep, assertOK := v.(*SoldReport)
+		if !assertOK {
+			err = errors.New("Query: Error asserting report to SoldReport")
+			log.Println(err)
+			return nil, err
+		}
+		reports = append(reports, *rep)
+	}
+	return reports, nil
+}
+
+// PurgeReports deletes the stored reports generated before the unix-timestamp,
+// and returns the number of deleted reports. If ctx is done first, the
+// ctx-error is returned, though the delete may still complete in background.
+func PurgeReports(
+	ctx context.Context,
+	before int64,
+	reportColl *mongo.Collection,
+) (int64, error) {
+	var deleteResult *mgo.DeleteResult
+	err := runMongoOp(ctx, func() error {
+		var err error
+		deleteResult, err = reportColl.DeleteMany(map[string]interface{}{
+			"timestamp": map[string]interface{}{
+				"$lt": before,
+			},
+		})
+		return err
+	})
+	if err != nil {
+		err = errors.Wrap(err, "Query: Error in purging reports")
+		log.Println(err)
+		return 0, err
+	}
+	return deleteResult.DeletedCount, nil
+}
+
+// StoreChart stores the chart alongside the report with the specified
+// reportID. Charts of the same type and format are replaced. If ctx is done
+// first, the ctx-error is returned.
+func StoreChart(
+	ctx context.Context,
+	reportID uuuid.UUID,
+	chart ReportChart,
+	reportColl *mongo.Collection,
+) error {
+	rep, err := FindReport(ctx, reportID, reportColl)
+	if err != nil {
+		return err
+	}
+
+	charts := []ReportChart{chart}
+	for _, c := range rep.Charts {
+		if c.Type != chart.Type || c.Format != chart.Format {
+			charts = append(charts, c)
+		}
+	}
+	err = runMongoOp(ctx, func() error {
+		_, err := reportColl.UpdateMany(
+			map[string]interface{}{
+				"reportID": map[string]interface{}{
+					"$eq": reportID.String(),
+				},
+			},
+			map[string]interface{}{
+				"charts": charts,
+			},
+		)
+		return err
+	})
+	if err != nil {
+		err = errors.Wrap(err, "Query: Error in storing chart")
+		log.Println(err)
+		return err
+	}
+	return nil
 }
@@ -173,7 +173,7 @@ var _ = Describe("Mongo service test", func() {
 		err := json.Unmarshal(searchParameters, &x)
 		Expect(err).ToNot(HaveOccurred())
 
-		avgSoldReport, err := ItemSoldReport(x, mgTable)
+		avgSoldReport, err := ItemSoldReport(ctx.Background(), x, mgTable)
 		Expect(err).ToNot(HaveOccurred())
 
 		log.Println(avgSoldReport, "*******************")
@@ -205,7 +205,7 @@ var _ = Describe("Mongo service test", func() {
 		err := json.Unmarshal(searchParameters, &x)
 		Expect(err).ToNot(HaveOccurred())
 
-		_, err = ItemSoldReport(x, mgTable)
+		_, err = ItemSoldReport(ctx.Background(), x, mgTable)
 		Expect(err).To(HaveOccurred())
 	})
 
@@ -216,7 +216,7 @@ var _ = Describe("Mongo service test", func() {
 		err := json.Unmarshal(searchParameters, &x)
 		Expect(err).ToNot(HaveOccurred())
 
-		_, err = ItemSoldReport(x, mgTable)
+		_, err = ItemSoldReport(ctx.Background(), x, mgTable)
 		Expect(err).To(HaveOccurred())
 	})
 
@@ -227,7 +227,7 @@ var _ = Describe("Mongo service test", func() {
 		err := json.Unmarshal(searchParameters, &x)
 		Expect(err).ToNot(HaveOccurred())
 
-		_, err = ItemSoldReport(x, mgTable)
+		_, err = ItemSoldReport(ctx.Background(), x, mgTable)
 		Expect(err).To(HaveOccurred())
 	})
 
@@ -238,7 +238,7 @@ var _ = Describe("Mongo service test", func() {
 		err := json.Unmarshal(searchParameters, &soldItemParams)
 		Expect(err).ToNot(HaveOccurred())
 
-		avgSoldReport, err := ItemSoldReport(soldItemParams, mgTable)
+		avgSoldReport, err := ItemSoldReport(ctx.Background(), soldItemParams, mgTable)
 		Expect(err).ToNot(HaveOccurred())
 
 		var reportAgg []ReportResult
@@ -286,7 +286,7 @@ var _ = Describe("Mongo service test", func() {
 			ReportResult: reportAgg,
 		}
 
-		_, err = CreateReport(reportGen, mgTable)
+		_, err = CreateReport(ctx.Background(), reportGen, mgTable)
 		Expect(err).ToNot(HaveOccurred())
 
 		var findResults []interface{}
@@ -308,3 +308,38 @@ var _ = Describe("Mongo service test", func() {
 	})
 
 })
+
+var _ = Describe("Report comparison", func() {
+	var rep SoldReport
+
+	BeforeEach(func() {
+		reportID, err := uuuid.NewV4()
+		Expect(err).ToNot(HaveOccurred())
+		rep = SoldReport{
+			ReportID:  reportID,
+			Timestamp: time.Now().Unix(),
+			Metric:    MetricSum,
+			ReportResult: []ReportResult{
+				{SKU: "sku1", Name: "name1", SoldWeight: 10, TotalWeight: 20},
+			},
+		}
+	})
+
+	It("matches a report with the same rows", func() {
+		stored := rep
+		stored.ReportResult = append([]ReportResult{}, rep.ReportResult...)
+		Expect(sameReport(&stored, &rep)).To(BeTrue())
+	})
+
+	It("doesn't match a different report with the same reportID", func() {
+		stored := rep
+		stored.Timestamp--
+		Expect(sameReport(&stored, &rep)).To(BeFalse())
+
+		stored = rep
+		stored.ReportResult = []ReportResult{
+			{SKU: "sku1", Name: "name1", SoldWeight: 11, TotalWeight: 20},
+		}
+		Expect(sameReport(&stored, &rep)).To(BeFalse())
+	})
+})
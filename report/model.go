@@ -21,8 +21,13 @@ type FlashSaleSoldItem struct {
 	Timestamp   int64             `bson:"timestamp,omitempty" json:"timestamp,omitempty"`
 }
 
+// SoldItemParams are the search-params for sold-items, and are used as the
+// $match stage of report-aggregations.
 type SoldItemParams struct {
 	Timestamp *Comparator `json:"timestamp,omitempty"`
+	SKU       *Comparator `json:"sku,omitempty"`
+	Name      *Comparator `json:"name,omitempty"`
+	Lot       *Comparator `json:"lot,omitempty"`
 }
 
 func (s FlashSaleSoldItem) MarshalBSON() ([]byte, error) {
@@ -44,7 +49,9 @@ func (s FlashSaleSoldItem) MarshalBSON() ([]byte, error) {
 	return bson.Marshal(si)
 }
 
-func (s FlashSaleSoldItem) UnmarshalBSON(in []byte) error {
+// UnmarshalBSON decodes the sold-item. It has a pointer-receiver, since a
+// value-receiver decodes into a copy, which left decoded items blank.
+func (s *FlashSaleSoldItem) UnmarshalBSON(in []byte) error {
 	m := make(map[string]interface{})
 	err := bson.Unmarshal(in, m)
 	if err != nil {
@@ -56,14 +63,18 @@ func (s FlashSaleSoldItem) UnmarshalBSON(in []byte) error {
 	return err
 }
 
-func (s FlashSaleSoldItem) unmarshalFromMap(m map[string]interface{}) error {
+func (s *FlashSaleSoldItem) unmarshalFromMap(m map[string]interface{}) error {
 	var err error
 	var assertOK bool
 
 	if m["_id"] != nil {
 		s.ID, assertOK = m["_id"].(objectid.ObjectID)
 		if !assertOK {
-			s.ID, err = objectid.FromHex(m["_id"].(string))
+			hexID, assertOK := m["_id"].(string)
+			if !assertOK {
+				return errors.New("Error while asserting ObjectID")
+			}
+			s.ID, err = objectid.FromHex(hexID)
 			if err != nil {
 				err = errors.Wrap(err, "Error while asserting ObjectID")
 				return err
@@ -71,8 +82,24 @@ func (s FlashSaleSoldItem) unmarshalFromMap(m map[string]interface{}) error {
 		}
 	}
 
+	if m["flashID"] != nil {
+		flashID, assertOK := m["flashID"].(string)
+		if !assertOK {
+			return errors.New("Error while asserting FlashID")
+		}
+		s.FlashID, err = uuuid.FromString(flashID)
+		if err != nil {
+			err = errors.Wrap(err, "Error while asserting FlashID")
+			return err
+		}
+	}
+
 	if m["itemID"] != nil {
-		s.ItemID, err = uuuid.FromString(m["itemID"].(string))
+		itemID, assertOK := m["itemID"].(string)
+		if !assertOK {
+			return errors.New("Error while asserting ItemID")
+		}
+		s.ItemID, err = uuuid.FromString(itemID)
 		if err != nil {
 			err = errors.Wrap(err, "Error while asserting ItemID")
 			return err
@@ -80,7 +107,11 @@ func (s FlashSaleSoldItem) unmarshalFromMap(m map[string]interface{}) error {
 	}
 
 	if m["saleID"] != nil {
-		s.SaleID, err = uuuid.FromString(m["saleID"].(string))
+		saleID, assertOK := m["saleID"].(string)
+		if !assertOK {
+			return errors.New("Error while asserting DeviceID")
+		}
+		s.SaleID, err = uuuid.FromString(saleID)
 		if err != nil {
 			err = errors.Wrap(err, "Error while asserting DeviceID")
 			return err
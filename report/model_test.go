@@ -0,0 +1,41 @@
+package report
+
+import (
+	"github.com/TerrexTech/uuuid"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("FlashSaleSoldItem", func() {
+	It("decodes into the receiver", func() {
+		flashID, err := uuuid.NewV4()
+		Expect(err).ToNot(HaveOccurred())
+
+		item := &FlashSaleSoldItem{}
+		err = item.unmarshalFromMap(map[string]interface{}{
+			"flashID":     flashID.String(),
+			"sku":         "test-sku",
+			"weight":      12.5,
+			"totalWeight": 20.0,
+			"timestamp":   int64(1539302400),
+		})
+		Expect(err).ToNot(HaveOccurred())
+		Expect(*item).To(Equal(FlashSaleSoldItem{
+			FlashID:     flashID,
+			SKU:         "test-sku",
+			Weight:      12.5,
+			TotalWeight: 20,
+			Timestamp:   1539302400,
+		}))
+	})
+
+	It("returns an error for IDs that aren't strings", func() {
+		for _, key := range []string{"_id", "flashID", "itemID", "saleID"} {
+			item := &FlashSaleSoldItem{}
+			err := item.unmarshalFromMap(map[string]interface{}{
+				key: 42,
+			})
+			Expect(err).To(HaveOccurred(), key)
+		}
+	})
+})
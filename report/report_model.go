@@ -12,6 +12,12 @@ type SoldReport struct {
 	ReportID     uuuid.UUID        `bson:"reportID,omitempty" json:"reportID,omitempty"`
 	SearchQuery  SoldItemParams    `bson:"searchQuery,omitempty" json:"searchQuery,omitempty"`
 	ReportResult []ReportResult    `bson:"reportResult,omitempty" json:"reportResult,omitempty"`
+	Timestamp    int64             `bson:"timestamp,omitempty" json:"timestamp,omitempty"`
+	Charts       []ReportChart     `bson:"charts,omitempty" json:"charts,omitempty"`
+	// Empty is set if no sold-items matched the SearchQuery.
+	Empty bool `bson:"empty" json:"empty"`
+	// Metric the weights were aggregated with. Blank is MetricAvg.
+	Metric string `bson:"metric,omitempty" json:"metric,omitempty"`
 }
 
 type SoldReportBSON struct {
@@ -19,20 +25,39 @@ type SoldReportBSON struct {
 	ReportID     string            `bson:"reportID,omitempty" json:"reportID,omitempty"`
 	SearchQuery  SoldItemParams    `bson:"searchQuery,omitempty" json:"searchQuery,omitempty"`
 	ReportResult []ReportResult    `bson:"reportResult,omitempty" json:"reportResult,omitempty"`
+	Timestamp    int64             `bson:"timestamp,omitempty" json:"timestamp,omitempty"`
+	Charts       []ReportChart     `bson:"charts,omitempty" json:"charts,omitempty"`
+	Empty        bool              `bson:"empty" json:"empty"`
+	Metric       string            `bson:"metric,omitempty" json:"metric,omitempty"`
+}
+
+// ReportChart is a chart-image stored alongside its report.
+type ReportChart struct {
+	Type      string `bson:"type,omitempty" json:"type,omitempty"`
+	Format    string `bson:"format,omitempty" json:"format,omitempty"`
+	Data      []byte `bson:"data,omitempty" json:"data,omitempty"`
+	Timestamp int64  `bson:"timestamp,omitempty" json:"timestamp,omitempty"`
 }
 
 type ReportResult struct {
 	SKU         string  `bson:"sku,omitempty" json:"sku,omitempty"`
 	Name        string  `bson:"name,omitempty" json:"name,omitempty"`
+	Lot         string  `bson:"lot,omitempty" json:"lot,omitempty"`
+	Bucket      int64   `bson:"bucket,omitempty" json:"bucket,omitempty"`
 	SoldWeight  float64 `bson:"soldWeight,omitempty" json:"soldWeight,omitempty"`
 	TotalWeight float64 `bson:"totalWeight,omitempty" json:"totalWeight,omitempty"`
+	// Count is the number of sold-items aggregated into the row.
+	Count int64 `bson:"count,omitempty" json:"count,omitempty"`
 }
 
 func (s SoldReport) MarshalBSON() ([]byte, error) {
+	// Keys must match the bson-tags on SoldReportBSON, so reports
+	// can be read back using UnmarshalBSON
 	sm := map[string]interface{}{
-		"reportid":     s.ReportID.String(),
-		"searchquery":  s.SearchQuery,
-		"reportresult": s.ReportResult,
+		"searchQuery":  s.SearchQuery,
+		"reportResult": s.ReportResult,
+		"timestamp":    s.Timestamp,
+		"empty":        s.Empty,
 	}
 	if s.ID != objectid.NilObjectID {
 		sm["_id"] = s.ID
@@ -42,6 +67,12 @@ func (s SoldReport) MarshalBSON() ([]byte, error) {
 	if s.ReportID != (uuuid.UUID{}) {
 		sm["reportID"] = s.ReportID.String()
 	}
+	if len(s.Charts) > 0 {
+		sm["charts"] = s.Charts
+	}
+	if s.Metric != "" {
+		sm["metric"] = s.Metric
+	}
 
 	return bson.Marshal(sm)
 }
@@ -62,6 +93,11 @@ func (s *SoldReport) UnmarshalBSON(in []byte) error {
 		err = errors.Wrap(err, "UnmarshalBSON Error: Error parsing SaleID")
 	}
 	s.ReportID = reportID
+	s.SearchQuery = sb.SearchQuery
+	s.Timestamp = sb.Timestamp
+	s.Charts = sb.Charts
+	s.Empty = sb.Empty
+	s.Metric = sb.Metric
 
 	if s.ReportResult == nil {
 		s.ReportResult = make([]ReportResult, 0)
@@ -70,6 +106,8 @@ func (s *SoldReport) UnmarshalBSON(in []byte) error {
 		s.ReportResult = append(s.ReportResult, ReportResult{
 			SKU:         v.SKU,
 			Name:        v.Name,
+			Lot:         v.Lot,
+			Bucket:      v.Bucket,
 			SoldWeight:  v.SoldWeight,
 			TotalWeight: v.TotalWeight,
 		})
@@ -0,0 +1,277 @@
+package report
+
+import (
+	"context"
+	"io"
+	"math/rand"
+	"net"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/mongodb/mongo-go-driver/core/command"
+	"github.com/mongodb/mongo-go-driver/core/connection"
+	"github.com/mongodb/mongo-go-driver/core/topology"
+	"github.com/pkg/errors"
+)
+
+// ErrCircuitOpen is returned without running the Mongo-operation while the
+// circuit-breaker is open.
+var ErrCircuitOpen = errors.New("circuit-breaker open: Mongo is unavailable")
+
+// Circuit-breaker states.
+const (
+	CircuitClosed   = "closed"
+	CircuitOpen     = "open"
+	CircuitHalfOpen = "half-open"
+)
+
+// RetryPolicy configures the retries of failed Mongo-operations. The delay
+// before each retry is random between zero and the exponential backoff,
+// which starts at BaseDelay and is capped at MaxDelay.
+type RetryPolicy struct {
+	// MaxAttempts is the number of attempts, including the first one.
+	MaxAttempts int
+	BaseDelay   time.Duration
+	MaxDelay    time.Duration
+}
+
+// backoff returns the jittered delay before the retry following the attempt.
+func (p RetryPolicy) backoff(attempt int) time.Duration {
+	delay := p.BaseDelay << uint(attempt-1)
+	if delay <= 0 || delay > p.MaxDelay {
+		delay = p.MaxDelay
+	}
+	if delay <= 0 {
+		return 0
+	}
+	return time.Duration(rand.Int63n(int64(delay) + 1))
+}
+
+// CircuitBreaker opens after Threshold consecutive failures, and fails
+// operations fast until Cooldown passed. A single trial-operation is then
+// allowed, which closes the circuit if it succeeds, or opens it again.
+type CircuitBreaker struct {
+	Threshold int
+	Cooldown  time.Duration
+	// OnStateChange is called with the new state whenever it changes.
+	OnStateChange func(state string)
+
+	lock     sync.Mutex
+	state    string
+	failures int
+	openedAt time.Time
+}
+
+// NewCircuitBreaker creates a closed CircuitBreaker.
+func NewCircuitBreaker(threshold int, cooldown time.Duration) *CircuitBreaker {
+	return &CircuitBreaker{
+		Threshold: threshold,
+		Cooldown:  cooldown,
+		state:     CircuitClosed,
+	}
+}
+
+// State returns the current state of the circuit.
+func (b *CircuitBreaker) State() string {
+	b.lock.Lock()
+	defer b.lock.Unlock()
+	return b.state
+}
+
+// allow returns ErrCircuitOpen if the operation should fail fast.
+func (b *CircuitBreaker) allow() error {
+	b.lock.Lock()
+	defer b.lock.Unlock()
+
+	switch b.state {
+	case CircuitOpen:
+		if time.Since(b.openedAt) < b.Cooldown {
+			return ErrCircuitOpen
+		}
+		b.setState(CircuitHalfOpen)
+		return nil
+	case CircuitHalfOpen:
+		// Only the trial-operation runs until it completes
+		return ErrCircuitOpen
+	default:
+		return nil
+	}
+}
+
+// record updates the circuit with the outcome of an allowed operation.
+// Errors that aren't caused by Mongo don't count as failures.
+func (b *CircuitBreaker) record(err error) {
+	b.lock.Lock()
+	defer b.lock.Unlock()
+
+	if err == nil || !IsRetryable(err) {
+		b.failures = 0
+		if b.state != CircuitClosed {
+			b.setState(CircuitClosed)
+		}
+		return
+	}
+
+	b.failures++
+	if b.state == CircuitHalfOpen || b.failures >= b.Threshold {
+		b.openedAt = time.Now()
+		if b.state != CircuitOpen {
+			b.setState(CircuitOpen)
+		}
+	}
+}
+
+// abandon releases the trial-operation of a half-open circuit without an
+// outcome, so the next operation becomes the trial.
+func (b *CircuitBreaker) abandon() {
+	b.lock.Lock()
+	defer b.lock.Unlock()
+
+	if b.state == CircuitHalfOpen {
+		b.setState(CircuitOpen)
+	}
+}
+
+// setState changes the state. The lock must be held by the caller.
+func (b *CircuitBreaker) setState(state string) {
+	b.state = state
+	if b.OnStateChange != nil {
+		b.OnStateChange(state)
+	}
+}
+
+// Resilience runs Mongo-operations with retries and a circuit-breaker.
+type Resilience struct {
+	Retry   RetryPolicy
+	Breaker *CircuitBreaker
+	// OnRetry is called before each retry, with the failed attempt.
+	OnRetry func(attempt int, err error)
+}
+
+// mongoOps guards all Mongo-operations of this package.
+var mongoOps = &Resilience{
+	Retry: RetryPolicy{
+		MaxAttempts: 3,
+		BaseDelay:   100 * time.Millisecond,
+		MaxDelay:    2 * time.Second,
+	},
+	Breaker: NewCircuitBreaker(5, 30*time.Second),
+}
+
+// SetResilience replaces the retry-policy and circuit-breaker used for
+// Mongo-operations. It should be called before any operation runs.
+func SetResilience(r *Resilience) {
+	mongoOps = r
+}
+
+// Run runs fn until it succeeds, fails with an error that isn't retryable,
+// or the attempts are exhausted. Each attempt is bounded by ctx, see
+// runWithContext. ErrCircuitOpen is returned while the circuit is open.
+func (r *Resilience) Run(ctx context.Context, fn func() error) error {
+	attempts := r.Retry.MaxAttempts
+	if attempts < 1 {
+		attempts = 1
+	}
+
+	var lastErr error
+	for attempt := 1; ; attempt++ {
+		err := r.attempt(ctx, fn)
+		// The circuit may open while retrying, in which case the failure
+		// that opened it is more useful to the caller
+		if err == ErrCircuitOpen && lastErr != nil {
+			return lastErr
+		}
+		if err == nil || attempt >= attempts || !IsRetryable(err) || ctx.Err() != nil {
+			return err
+		}
+		lastErr = err
+		if r.OnRetry != nil {
+			r.OnRetry(attempt, err)
+		}
+
+		timer := time.NewTimer(r.Retry.backoff(attempt))
+		select {
+		case <-timer.C:
+		case <-ctx.Done():
+			timer.Stop()
+			return ctx.Err()
+		}
+	}
+}
+
+func (r *Resilience) attempt(ctx context.Context, fn func() error) error {
+	if r.Breaker == nil {
+		return runWithContext(ctx, fn)
+	}
+
+	err := r.Breaker.allow()
+	if err != nil {
+		return err
+	}
+	err = runWithContext(ctx, fn)
+	// Abandoned operations say nothing about Mongo's availability
+	if ctx.Err() != nil {
+		r.Breaker.abandon()
+		return err
+	}
+	r.Breaker.record(err)
+	return err
+}
+
+// runMongoOp runs the Mongo-operation with the package's Resilience.
+func runMongoOp(ctx context.Context, fn func() error) error {
+	return mongoOps.Run(ctx, fn)
+}
+
+// retryableCodes are the codes of Mongo server-errors returned during
+// failovers and network failures between the nodes, such as NotMaster and
+// InterruptedDueToReplStateChange.
+var retryableCodes = map[int32]bool{
+	6:     true, // HostUnreachable
+	7:     true, // HostNotFound
+	89:    true, // NetworkTimeout
+	91:    true, // ShutdownInProgress
+	189:   true, // PrimarySteppedDown
+	9001:  true, // SocketException
+	10107: true, // NotMaster
+	11600: true, // InterruptedAtShutdown
+	11602: true, // InterruptedDueToReplStateChange
+	13435: true, // NotMasterNoSlaveOk
+	13436: true, // NotMasterOrSecondary
+}
+
+// IsRetryable returns true if err was caused by a transient failure, which
+// may succeed when retried. Context-errors and ErrCircuitOpen are not
+// retryable. Connection-errors of the driver are only retryable if they
+// wrap a network-error, so failed authentication isn't retried.
+func IsRetryable(err error) bool {
+	cause := errors.Cause(err)
+	switch cause {
+	case nil, context.Canceled, context.DeadlineExceeded, ErrCircuitOpen:
+		return false
+	case io.EOF, io.ErrUnexpectedEOF, topology.ErrServerSelectionTimeout:
+		return true
+	}
+
+	switch e := cause.(type) {
+	case net.Error:
+		return true
+	case connection.Error:
+		return IsRetryable(e.Wrapped)
+	case *connection.Error:
+		return IsRetryable(e.Wrapped)
+	case command.Error:
+		return retryableCodes[e.Code]
+	case *command.Error:
+		return retryableCodes[e.Code]
+	}
+	return false
+}
+
+// isDuplicateKeyError returns true if err was caused by a violated unique
+// index.
+func isDuplicateKeyError(err error) bool {
+	msg := errors.Cause(err).Error()
+	return strings.Contains(msg, "E11000") || strings.Contains(msg, "duplicate key")
+}
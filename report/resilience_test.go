@@ -0,0 +1,125 @@
+package report
+
+import (
+	"context"
+	"io"
+	"net"
+	"time"
+
+	"github.com/mongodb/mongo-go-driver/core/command"
+	"github.com/mongodb/mongo-go-driver/core/connection"
+	"github.com/mongodb/mongo-go-driver/core/topology"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	"github.com/pkg/errors"
+)
+
+var _ = Describe("Resilience", func() {
+	var r *Resilience
+
+	BeforeEach(func() {
+		r = &Resilience{
+			Retry: RetryPolicy{
+				MaxAttempts: 3,
+				BaseDelay:   time.Millisecond,
+				MaxDelay:    5 * time.Millisecond,
+			},
+			Breaker: NewCircuitBreaker(2, 20*time.Millisecond),
+		}
+	})
+
+	It("retries transient failures", func() {
+		r.Breaker.Threshold = 5
+		attempts := 0
+		err := r.Run(context.Background(), func() error {
+			attempts++
+			if attempts < 3 {
+				return errors.Wrap(io.EOF, "test")
+			}
+			return nil
+		})
+		Expect(err).ToNot(HaveOccurred())
+		Expect(attempts).To(Equal(3))
+	})
+
+	It("doesn't retry other errors", func() {
+		attempts := 0
+		err := r.Run(context.Background(), func() error {
+			attempts++
+			return errors.New("duplicate key")
+		})
+		Expect(err).To(MatchError("duplicate key"))
+		Expect(attempts).To(Equal(1))
+		Expect(r.Breaker.State()).To(Equal(CircuitClosed))
+	})
+
+	It("fails fast while the circuit is open", func() {
+		err := r.Run(context.Background(), func() error {
+			return &net.OpError{Op: "dial", Err: errors.New("connection refused")}
+		})
+		Expect(err).To(MatchError("dial: connection refused"))
+		Expect(r.Breaker.State()).To(Equal(CircuitOpen))
+
+		called := false
+		err = r.Run(context.Background(), func() error {
+			called = true
+			return nil
+		})
+		Expect(err).To(Equal(ErrCircuitOpen))
+		Expect(called).To(BeFalse())
+	})
+
+	It("closes the circuit after a successful trial", func() {
+		r.Retry.MaxAttempts = 1
+		for i := 0; i < 2; i++ {
+			r.Run(context.Background(), func() error {
+				return io.EOF
+			})
+		}
+		Expect(r.Breaker.State()).To(Equal(CircuitOpen))
+
+		time.Sleep(30 * time.Millisecond)
+		err := r.Run(context.Background(), func() error {
+			return nil
+		})
+		Expect(err).ToNot(HaveOccurred())
+		Expect(r.Breaker.State()).To(Equal(CircuitClosed))
+	})
+
+	It("stops retrying when ctx is done", func() {
+		ctx, cancel := context.WithCancel(context.Background())
+		attempts := 0
+		err := r.Run(ctx, func() error {
+			attempts++
+			cancel()
+			return io.EOF
+		})
+		Expect(err).To(HaveOccurred())
+		Expect(attempts).To(Equal(1))
+	})
+
+	It("classifies retryable errors", func() {
+		Expect(IsRetryable(io.ErrUnexpectedEOF)).To(BeTrue())
+		Expect(IsRetryable(errors.Wrap(topology.ErrServerSelectionTimeout, "test"))).To(BeTrue())
+		Expect(IsRetryable(command.Error{Code: 10107, Message: "not master"})).To(BeTrue())
+		Expect(IsRetryable(connection.Error{
+			Wrapped: &net.OpError{Op: "read", Err: errors.New("connection reset by peer")},
+		})).To(BeTrue())
+		Expect(IsRetryable(context.DeadlineExceeded)).To(BeFalse())
+		Expect(IsRetryable(ErrCircuitOpen)).To(BeFalse())
+		Expect(IsRetryable(errors.New("invalid pipeline"))).To(BeFalse())
+		// Auth- and connection-string errors mention connections too
+		Expect(IsRetryable(connection.Error{
+			Wrapped: errors.New("auth error: unable to authenticate using mechanism"),
+		})).To(BeFalse())
+		Expect(IsRetryable(errors.New("error parsing connection string"))).To(BeFalse())
+		Expect(IsRetryable(command.Error{Code: 18, Message: "Authentication failed."})).To(BeFalse())
+	})
+	It("detects duplicate-key errors", func() {
+		Expect(isDuplicateKeyError(errors.Wrap(
+			errors.New("E11000 duplicate key error collection: db.reports index: reportID_index"),
+			"test",
+		))).To(BeTrue())
+		Expect(isDuplicateKeyError(io.EOF)).To(BeFalse())
+	})
+})
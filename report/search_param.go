@@ -6,6 +6,27 @@ type Comparator struct {
 	Eq interface{} `json:"$eq,omitempty"`
 }
 
+// Validate checks that the search-params have a timestamp-range. Reports
+// always require a range, so they don't aggregate all sold-items.
+func (s SoldItemParams) Validate() error {
+	fields := fieldErrors{}
+	switch {
+	case s.Timestamp == nil:
+		fields.add("timestamp", "is required")
+	default:
+		if s.Timestamp.Gt == 0 {
+			fields.add("timestamp.$gt", "is required")
+		}
+		if s.Timestamp.Lt == 0 {
+			fields.add("timestamp.$lt", "is required")
+		}
+		if s.Timestamp.Gt != 0 && s.Timestamp.Lt != 0 && s.Timestamp.Gt >= s.Timestamp.Lt {
+			fields.add("timestamp.$gt", "must be less than $lt")
+		}
+	}
+	return fields.err()
+}
+
 // {
 // 	sku: {
 // 		$eq: "trestsda",
@@ -0,0 +1,114 @@
+package report
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/pkg/errors"
+)
+
+// FieldError describes an invalid field of a request. Field is the JSON-path
+// of the field, such as "timestamp.$lt" or "aggregation.groupBy[1]".
+type FieldError struct {
+	Field   string `json:"field"`
+	Message string `json:"message"`
+}
+
+// InvalidFieldsError is returned when a request has invalid fields, and
+// lists all of them.
+type InvalidFieldsError struct {
+	Fields []FieldError
+}
+
+func (e *InvalidFieldsError) Error() string {
+	msgs := make([]string, len(e.Fields))
+	for i, f := range e.Fields {
+		msgs[i] = f.Field + ": " + f.Message
+	}
+	return "invalid fields: " + strings.Join(msgs, "; ")
+}
+
+// InvalidFields returns the invalid fields, if err was caused by an
+// InvalidFieldsError.
+func InvalidFields(err error) ([]FieldError, bool) {
+	fieldsErr, ok := errors.Cause(err).(*InvalidFieldsError)
+	if !ok {
+		return nil, false
+	}
+	return fieldsErr.Fields, true
+}
+
+// fieldErrors collects the invalid fields of a request.
+type fieldErrors []FieldError
+
+func (f *fieldErrors) add(field string, format string, args ...interface{}) {
+	*f = append(*f, FieldError{
+		Field:   field,
+		Message: fmt.Sprintf(format, args...),
+	})
+}
+
+// addErr adds the invalid fields of err with the prefix prepended to
+// their paths. Errors without invalid fields are added for the prefix.
+func (f *fieldErrors) addErr(prefix string, err error) {
+	if err == nil {
+		return
+	}
+	fields, ok := InvalidFields(err)
+	if !ok {
+		f.add(prefix, "%s", err.Error())
+		return
+	}
+	for _, field := range fields {
+		*f = append(*f, FieldError{
+			Field:   joinField(prefix, field.Field),
+			Message: field.Message,
+		})
+	}
+}
+
+// err returns an InvalidFieldsError if any field is invalid, or nil.
+func (f fieldErrors) err() error {
+	if len(f) == 0 {
+		return nil
+	}
+	return &InvalidFieldsError{
+		Fields: f,
+	}
+}
+
+// joinField joins the JSON-paths, such that array-indices are not
+// separated by a dot.
+func joinField(prefix string, field string) string {
+	if prefix == "" {
+		return field
+	}
+	if field == "" {
+		return prefix
+	}
+	if strings.HasPrefix(field, "[") {
+		return prefix + field
+	}
+	return prefix + "." + field
+}
+
+// PrefixFields prepends the prefix to the paths of the invalid fields in
+// err, such as for options nested in a request. Other errors are returned
+// unchanged.
+func PrefixFields(prefix string, err error) error {
+	if _, ok := InvalidFields(err); !ok {
+		return err
+	}
+	fields := fieldErrors{}
+	fields.addErr(prefix, err)
+	return fields.err()
+}
+
+// ErrorDetails is the Result of error-responses, so clients can handle
+// errors without parsing the error-message.
+type ErrorDetails struct {
+	// Reason is the error-category, such as "validation" or "not-found".
+	Reason string `json:"reason"`
+	// Fields lists the invalid fields of validation-errors.
+	Fields []FieldError `json:"fields,omitempty"`
+}
@@ -0,0 +1,60 @@
+package report
+
+import (
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	"github.com/pkg/errors"
+)
+
+var _ = Describe("Validation", func() {
+	It("lists the invalid timestamp-fields", func() {
+		err := SoldItemParams{}.Validate()
+		fields, ok := InvalidFields(err)
+		Expect(ok).To(BeTrue())
+		Expect(fields).To(Equal([]FieldError{
+			FieldError{Field: "timestamp", Message: "is required"},
+		}))
+
+		err = SoldItemParams{
+			Timestamp: &Comparator{Gt: 20, Lt: 10},
+		}.Validate()
+		fields, ok = InvalidFields(err)
+		Expect(ok).To(BeTrue())
+		Expect(fields).To(Equal([]FieldError{
+			FieldError{Field: "timestamp.$gt", Message: "must be less than $lt"},
+		}))
+
+		Expect(SoldItemParams{
+			Timestamp: &Comparator{Gt: 10, Lt: 20},
+		}.Validate()).To(Succeed())
+	})
+
+	It("finds the invalid fields of wrapped errors", func() {
+		err := errors.Wrap(SoldItemParams{}.Validate(), "test")
+		_, ok := InvalidFields(err)
+		Expect(ok).To(BeTrue())
+
+		_, ok = InvalidFields(errors.New("test"))
+		Expect(ok).To(BeFalse())
+	})
+
+	It("prefixes the invalid fields", func() {
+		err := PrefixFields("aggregation", AggregationSpec{
+			GroupBy: []string{"sku", "color"},
+		}.Validate())
+		fields, ok := InvalidFields(err)
+		Expect(ok).To(BeTrue())
+		Expect(fields).To(HaveLen(1))
+		Expect(fields[0].Field).To(Equal("aggregation.groupBy[1]"))
+
+		err = PrefixFields("aggregation", errors.New("test"))
+		Expect(err).To(MatchError("test"))
+	})
+
+	It("joins the field-paths", func() {
+		Expect(joinField("", "a")).To(Equal("a"))
+		Expect(joinField("a", "")).To(Equal("a"))
+		Expect(joinField("a", "b")).To(Equal("a.b"))
+		Expect(joinField("a", "[0].b")).To(Equal("a[0].b"))
+	})
+})
@@ -0,0 +1,7 @@
+// Package reportpb contains the protobuf messages and gRPC service for
+// generating and retrieving sold-item flash-sale reports.
+package reportpb
+
+// report.pb.go is generated using protoc-gen-go v1.2.0, matching the locked
+// github.com/golang/protobuf version.
+//go:generate protoc --go_out=plugins=grpc:. report.proto
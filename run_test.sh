@@ -44,6 +44,31 @@ do
   sleep 1
 done
 
+function ping_kafka_topics() {
+  topics=$(docker exec kafka kafka-topics.sh --list --zookeeper zookeeper:2181)
+  echo "$topics" | grep -qx event.persistence.response.14 && \
+    echo "$topics" | grep -qx esquery.response.14
+  res=$?
+}
+
+# The consumed topics are created by the Kafka container, and are required
+# at startup of agg-itemsoldflashsale-report
+echo "Waiting for Kafka topics to be created."
+max_attempts=30
+cur_attempts=0
+ping_kafka_topics
+while (( res != 0 && ++cur_attempts != max_attempts ))
+do
+  ping_kafka_topics
+  echo Attempt: $cur_attempts of $max_attempts
+  sleep 1
+done
+
+if (( cur_attempts == max_attempts )); then
+  echo "Kafka topics Timed Out."
+  exit 1
+fi
+
 docker-compose up -d --build --force-recreate go-eventpersistence
 echo "Waiting for go-eventpersistence to initialize"
 sleep 5